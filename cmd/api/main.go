@@ -2,19 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"face-recognition-api/internal/auth"
 	"face-recognition-api/internal/config"
 	"face-recognition-api/internal/handlers"
+	"face-recognition-api/internal/health"
+	"face-recognition-api/internal/jobs"
 	"face-recognition-api/internal/middleware"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/ratelimit"
+	"face-recognition-api/internal/requestid"
 	"face-recognition-api/internal/services"
+	"face-recognition-api/internal/tracing"
 )
 
 func main() {
@@ -22,52 +33,223 @@ func main() {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
+	logger.AddHook(requestid.LogHook{})
+	logger.AddHook(auth.LogHook{})
 
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
 
 	logger.WithFields(logrus.Fields{
-		"port":           cfg.Server.Port,
-		"read_timeout":   cfg.Server.ReadTimeout,
-		"write_timeout":  cfg.Server.WriteTimeout,
-		"max_image_size": cfg.Limits.MaxImageSize,
+		"port":              cfg.Server.Port,
+		"read_timeout":      cfg.Server.ReadTimeout,
+		"write_timeout":     cfg.Server.WriteTimeout,
+		"max_image_size":    cfg.Limits.MaxImageSize,
+		"batch_parallelism": cfg.Batch.Parallelism,
 	}).Info("Starting Face Recognition API")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
 	// Initialize services
 	faceDetector, err := services.NewFaceDetector(cfg.Pigo, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize face detector")
 	}
+	skinDetector := services.NewSkinDetector(cfg.Skin)
+
+	detectors, err := services.NewDetectorRegistry(cfg.Detection, faceDetector, skinDetector)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize detection backends")
+	}
+	logger.WithFields(logrus.Fields{
+		"default":  cfg.Detection.Backend,
+		"profiles": cfg.Detection.Profiles,
+	}).Info("Detection backends registered")
 
+	detectionPool := services.NewDetectionPool(cfg.Pool, logger)
 	imageDownloader := services.NewImageDownloader(cfg.Limits, logger)
 	imageProcessor := services.NewImageProcessor(logger)
 
+	webhookDispatcher := services.NewWebhookDispatcher(cfg.Webhook, logger)
+	webhookDispatcher.Start()
+
+	// Shadow detection compares a candidate configuration against live traffic
+	var shadowRunner *services.ShadowRunner
+	var shadowPigo *services.FaceDetector
+	if cfg.Shadow.Enabled {
+		shadowRunner, shadowPigo, err = newShadowRunner(cfg, skinDetector, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize shadow detection")
+		}
+		shadowRunner.Start()
+		logger.WithFields(logrus.Fields{
+			"detector":    cfg.Shadow.Detector,
+			"version":     shadowRunner.Detector().Version(),
+			"sample_rate": cfg.Shadow.SampleRate,
+		}).Info("Shadow detection enabled")
+	}
+
 	// Initialize handlers
-	faceHandler := handlers.NewFaceHandler(faceDetector, imageDownloader, imageProcessor, logger)
-	healthHandler := handlers.NewHealthHandler(logger)
+	faceHandler := handlers.NewFaceHandler(detectors, detectionPool, imageDownloader, imageProcessor, webhookDispatcher, shadowRunner, cfg.Batch, logger)
+
+	// Initialize asynchronous job processing
+	jobStore, err := newJobStore(cfg.Jobs, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize job store")
+	}
+
+	jobManager := jobs.NewManager(cfg.Jobs, jobStore, faceHandler, webhookDispatcher, logger)
+	if err := jobManager.Start(); err != nil {
+		logger.WithError(err).Fatal("Failed to start job manager")
+	}
+
+	jobHandler := handlers.NewJobHandler(jobManager, logger)
+
+	// Initialize readiness checks; the first run happens before the server accepts traffic.
+	// The self-test runs every backend with its default parameters, so reloading tuned
+	// parameters can't fail readiness on the reference portrait.
+	defaults := config.Default()
+	selfTestDetectors := []services.Detector{
+		faceDetector.WithConfig(defaults.Pigo),
+		services.NewSkinDetector(defaults.Skin),
+	}
+	healthChecker := health.NewChecker(cfg.Health, logger)
+	healthChecker.Register("self_test", func(ctx context.Context) error {
+		for _, detector := range selfTestDetectors {
+			if err := services.SelfTest(ctx, detector, cfg.Health.SelfTestBudget); err != nil {
+				return fmt.Errorf("%s: %w", detector.Name(), err)
+			}
+		}
+		return nil
+	})
+	healthChecker.Register("detection_pool", health.SaturationCheck(detectionPool.Saturation, cfg.Health.MaxPoolSaturation))
+	healthChecker.Register("heap", health.HeapCheck(cfg.Health.MaxHeapBytes))
+	healthChecker.Start()
+
+	healthHandler := handlers.NewHealthHandler(healthChecker, logger)
+
+	// Initialize authentication
+	var apiKeys *auth.KeyStore
+	var tokenVerifier *auth.TokenVerifier
+	if cfg.Auth.Enabled {
+		apiKeys, tokenVerifier, err = loadCredentials(cfg.Auth, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize authentication")
+		}
+	}
+	authn := middleware.NewAuth(apiKeys, tokenVerifier, logger)
+
+	// Initialize rate limiting
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		backend, err := newRateLimitBackend(cfg.RateLimit)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize rate limiter")
+		}
+		limiter = ratelimit.New(cfg.RateLimit, backend)
+		logger.WithFields(logrus.Fields{
+			"backend":       cfg.RateLimit.Backend,
+			"daily_quota":   cfg.RateLimit.DailyQuota,
+			"monthly_quota": cfg.RateLimit.MonthlyQuota,
+		}).Info("Rate limiting enabled")
+	}
+	rateLimit := middleware.NewRateLimit(limiter, cfg.RateLimit.TrustProxy, logger)
+
+	// Reload detector parameters, limits, credentials and rate limits on SIGHUP or file change.
+	// Everything is loaded before anything is swapped, so a bad reload changes nothing.
+	reloader := config.NewReloader(cfg, func(next *config.Config) error {
+		var keys *auth.KeyStore
+		var tokens *auth.TokenVerifier
+		var err error
+		if cfg.Auth.Enabled {
+			keys, tokens, err = loadCredentials(next.Auth, logger)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Cascades may need loading for both pigo detectors; both are loaded before either
+		// is swapped, so a failure leaves them both unchanged. Swaps below can't fail.
+		applyShadow := func() {}
+		if shadowPigo != nil {
+			if applyShadow, err = shadowPigo.PrepareConfig(next.ShadowPigo()); err != nil {
+				return fmt.Errorf("shadow: %w", err)
+			}
+		}
+		applyPigo, err := faceDetector.PrepareConfig(next.Pigo)
+		if err != nil {
+			return err
+		}
+		applyShadow()
+		applyPigo()
+		skinDetector.SetConfig(next.Skin)
+		imageDownloader.SetLimits(next.Limits)
+		if cfg.Auth.Enabled {
+			authn.SetCredentials(keys, tokens)
+		}
+		if limiter != nil {
+			limiter.Update(next.RateLimit)
+		}
+		return nil
+	}, logger)
+	reloader.Start()
 
 	// Setup router
 	router := mux.NewRouter()
 
 	// Apply global middleware
+	inFlight := &middleware.InFlight{}
+	router.Use(inFlight.Middleware())
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
 	router.Use(middleware.LoggingMiddleware(logger))
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(authn.Authenticate())
 	router.Use(middleware.RecoveryMiddleware(logger))
+	if shadowRunner != nil {
+		router.Use(shadowRunner.Middleware())
+	}
 
 	// API routes
 	api := router.PathPrefix("/api/v1").Subrouter()
 	
+	// secured applies the rate limits of class and requires credentials granting scope;
+	// an empty scope only requires valid credentials
+	secured := func(class ratelimit.Class, scope auth.Scope, handler http.HandlerFunc) http.Handler {
+		return rateLimit.Limit(class)(authn.Require(scope)(handler))
+	}
+	// operational leaves health and metrics endpoints public unless configured otherwise
+	operational := func(public bool, handler http.Handler) http.Handler {
+		if public {
+			return handler
+		}
+		return authn.Require(auth.ScopeAdmin)(handler)
+	}
+
 	// Face detection endpoints
-	api.HandleFunc("/detect", faceHandler.DetectHandler).Methods("POST")
-	api.HandleFunc("/validate", faceHandler.ValidateHandler).Methods("POST")
-	api.HandleFunc("/detect-visual", faceHandler.DetectVisualHandler).Methods("POST")
+	api.Handle("/detect", secured(ratelimit.ClassDetect, auth.ScopeDetect, faceHandler.DetectHandler)).Methods("POST")
+	api.Handle("/detect/batch", secured(ratelimit.ClassBatch, auth.ScopeDetect, faceHandler.DetectBatchHandler)).Methods("POST")
+	api.Handle("/validate", secured(ratelimit.ClassDetect, auth.ScopeValidate, faceHandler.ValidateHandler)).Methods("POST")
+	api.Handle("/detect-visual", secured(ratelimit.ClassVisual, auth.ScopeVisual, faceHandler.DetectVisualHandler)).Methods("POST")
+	api.Handle("/detectors", secured(ratelimit.ClassStatus, "", faceHandler.DetectorsHandler)).Methods("GET")
+	
+	// Asynchronous job endpoints; scopes depend on the job type and owner
+	api.Handle("/jobs", secured(ratelimit.ClassDetect, "", jobHandler.CreateHandler)).Methods("POST")
+	api.Handle("/jobs/{id}", secured(ratelimit.ClassStatus, "", jobHandler.GetHandler)).Methods("GET")
+	api.Handle("/jobs/{id}", secured(ratelimit.ClassStatus, "", jobHandler.CancelHandler)).Methods("DELETE")
 	
 	// Health check endpoints
-	api.HandleFunc("/health", healthHandler.HealthHandler).Methods("GET")
-	api.HandleFunc("/ready", healthHandler.ReadinessHandler).Methods("GET")
-	api.HandleFunc("/live", healthHandler.LivenessHandler).Methods("GET")
+	api.Handle("/health", operational(cfg.Auth.PublicHealth, http.HandlerFunc(healthHandler.HealthHandler))).Methods("GET")
+	api.Handle("/ready", operational(cfg.Auth.PublicHealth, http.HandlerFunc(healthHandler.ReadinessHandler))).Methods("GET")
+	api.Handle("/live", operational(cfg.Auth.PublicHealth, http.HandlerFunc(healthHandler.LivenessHandler))).Methods("GET")
 
 	// Metrics endpoint
-	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	router.Handle("/metrics", operational(cfg.Auth.PublicMetrics, promhttp.Handler())).Methods("GET")
 
 	// Root endpoint
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +258,51 @@ func main() {
 		w.Write([]byte(`{"service":"face-recognition-api","version":"1.0.0","status":"running"}`))
 	}).Methods("GET")
 
+	// Admin API on its own listener, so it can be kept off the public network
+	var adminServer *http.Server
+	if cfg.Admin.Enabled {
+		adminHandler := handlers.NewAdminHandler(reloader, faceDetector, detectionPool, jobManager, webhookDispatcher, authn, limiter, inFlight, logger)
+
+		adminRouter := mux.NewRouter()
+		adminRouter.Use(middleware.RequestIDMiddleware())
+		adminRouter.Use(middleware.LoggingMiddleware(logger))
+		adminRouter.Use(authn.Authenticate())
+		adminRouter.Use(authn.Require(auth.ScopeAdmin))
+		adminRouter.Use(middleware.RecoveryMiddleware(logger))
+
+		admin := adminRouter.PathPrefix("/admin").Subrouter()
+		admin.HandleFunc("/config", adminHandler.ConfigHandler).Methods("GET")
+		admin.HandleFunc("/pigo", adminHandler.GetPigoHandler).Methods("GET")
+		admin.HandleFunc("/pigo", adminHandler.UpdatePigoHandler).Methods("PATCH")
+		admin.HandleFunc("/log-level", adminHandler.GetLogLevelHandler).Methods("GET")
+		admin.HandleFunc("/log-level", adminHandler.SetLogLevelHandler).Methods("PUT")
+		admin.HandleFunc("/stats", adminHandler.StatsHandler).Methods("GET")
+		admin.HandleFunc("/cascade/reload", adminHandler.ReloadCascadeHandler).Methods("POST")
+		admin.HandleFunc("/rate-limit/reset", adminHandler.ResetRateLimitHandler).Methods("POST")
+
+		// Profiling; the index links to named profiles relative to its own path
+		admin.HandleFunc("/debug/pprof/", pprof.Index)
+		admin.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		admin.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		admin.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		admin.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		admin.HandleFunc("/debug/pprof/{profile}", adminHandler.ProfileHandler)
+
+		// No write timeout: CPU profiles and traces stream for as long as requested
+		adminServer = &http.Server{
+			Addr:        cfg.Admin.Addr,
+			Handler:     adminRouter,
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+		go func() {
+			logger.WithField("addr", cfg.Admin.Addr).Info("Admin server starting")
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.WithError(err).Fatal("Admin server failed to start")
+			}
+		}()
+	}
+
 	// Configure HTTP server
 	server := &http.Server{
 		Addr:         cfg.Server.Port,
@@ -98,16 +325,141 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// Fail readiness first and keep serving while load balancers stop routing here;
+	// a second signal skips the wait
+	healthChecker.SetDraining()
+	logger.WithField("pre_stop_delay", cfg.Server.PreStopDelay.String()).Info("Draining, readiness now failing")
+	select {
+	case <-time.After(cfg.Server.PreStopDelay):
+	case <-quit:
+	}
+
 	logger.Info("Server shutting down...")
 
 	// Create a deadline for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	// Stop accepting jobs and wait for queued and running ones while in-flight requests finish
+	jobsDrained := make(chan struct{})
+	go func() {
+		defer close(jobsDrained)
+		running, queued := jobManager.Drain(ctx)
+		if len(running)+len(queued) > 0 {
+			logger.WithFields(logrus.Fields{
+				"running_jobs": running,
+				"queued_jobs":  queued,
+			}).Warn("Unfinished jobs were abandoned")
+		} else {
+			logger.Info("Job queue drained")
+		}
+	}()
+
 	// Attempt graceful shutdown
 	if err := server.Shutdown(ctx); err != nil {
-		logger.WithError(err).Error("Server forced to shutdown")
+		logger.WithError(err).WithField("requests", inFlight.Count()).Error("Server forced to shutdown, in-flight requests were abandoned")
+		server.Close()
 	} else {
 		logger.Info("Server shutdown complete")
 	}
+	<-jobsDrained
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			adminServer.Close()
+		}
+	}
+
+	// Callbacks of the requests and jobs that just finished are delivered last
+	if err := webhookDispatcher.Stop(ctx); err != nil {
+		logger.WithError(err).Warn("Pending webhook deliveries were abandoned")
+	} else {
+		logger.Info("Webhook deliveries completed")
+	}
+
+	if shadowRunner != nil {
+		if err := shadowRunner.Stop(ctx); err != nil {
+			logger.WithError(err).Warn("Pending shadow comparisons were abandoned")
+		}
+	}
+
+	reloader.Stop()
+	healthChecker.Stop()
+
+	// Flush traces even when draining used up the deadline
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.WithError(err).Warn("Failed to flush traces")
+	}
+}
+
+// loadCredentials loads the API keys and JWT issuers of cfg. Credential types without
+// entries are returned as nil, so they are neither advertised nor checked.
+func loadCredentials(cfg config.AuthConfig, logger *logrus.Logger) (*auth.KeyStore, *auth.TokenVerifier, error) {
+	keys, err := auth.NewKeyStore(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load API keys: %w", err)
+	}
+	tokens, err := auth.NewTokenVerifier(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load JWT issuers: %w", err)
+	}
+	if keys.Len() == 0 && tokens.Len() == 0 {
+		return nil, nil, errors.New("authentication is enabled but no API keys or JWT issuers are configured")
+	}
+	logger.WithFields(logrus.Fields{
+		"api_keys":    keys.Len(),
+		"jwt_issuers": tokens.Len(),
+	}).Info("Authentication credentials loaded")
+
+	if keys.Len() == 0 {
+		keys = nil
+	}
+	if tokens.Len() == 0 {
+		tokens = nil
+	}
+	return keys, tokens, nil
+}
+
+// newShadowRunner creates the shadow runner of cfg. Pigo runs as a detector of its own, so
+// its parameters can differ from those serving responses; it is returned for reloads.
+func newShadowRunner(cfg *config.Config, skin *services.SkinDetector, logger *logrus.Logger) (*services.ShadowRunner, *services.FaceDetector, error) {
+	pigo, err := services.NewFaceDetector(cfg.ShadowPigo(), logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize shadow pigo detector: %w", err)
+	}
+
+	backend, cascade, _ := strings.Cut(cfg.Shadow.Detector, ":")
+	candidates, err := services.NewDetectorRegistry(config.DetectionConfig{Backend: backend}, pigo, skin)
+	if err != nil {
+		return nil, nil, err
+	}
+	detector, err := candidates.Select(models.DetectorSelection{Detector: backend, Cascade: cascade})
+	if err != nil {
+		return nil, nil, err
+	}
+	return services.NewShadowRunner(cfg.Shadow, detector, logger), pigo, nil
+}
+
+// newRateLimitBackend creates the rate limiter backend selected by configuration
+func newRateLimitBackend(cfg config.RateLimitConfig) (ratelimit.Backend, error) {
+	switch cfg.Backend {
+	case "memory":
+		return ratelimit.NewMemoryBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.Backend)
+	}
+}
+
+// newJobStore creates the job store selected by configuration
+func newJobStore(cfg config.JobsConfig, logger *logrus.Logger) (jobs.Store, error) {
+	switch cfg.Store {
+	case "memory":
+		return jobs.NewMemoryStore(), nil
+	case "file":
+		return jobs.NewFileStore(cfg.StoreDir, logger)
+	default:
+		return nil, fmt.Errorf("unknown job store: %s", cfg.Store)
+	}
 }
\ No newline at end of file
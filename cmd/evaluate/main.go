@@ -0,0 +1,170 @@
+// Command evaluate measures detection accuracy against a labeled dataset. It runs a
+// detection backend, configured like the API through CONFIG_FILE and environment
+// variables, over every annotated image and reports precision, recall, F1 and average
+// precision per IoU threshold and face size, with a sweep over the min confidence.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/evaluation"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/services"
+)
+
+func main() {
+	format := flag.String("format", "json", "annotation format: "+strings.Join(evaluation.Formats, ", "))
+	annotations := flag.String("annotations", "", "annotation file, or a glob matching several (e.g. FDDB folds)")
+	images := flag.String("images", ".", "directory annotation image paths are relative to")
+	detectorFlag := flag.String("detector", "", "backend, optionally with a cascade as backend:cascade (default detection.backend)")
+	minConfidence := flag.Float64("min-confidence", -1, "operating point to report at (default the backend's configured min confidence)")
+	iou := flag.String("iou", "0.5", "comma-separated IoU thresholds")
+	sizes := flag.String("sizes", "32,96", "comma-separated face size bucket edges, in pixels of sqrt(width*height)")
+	sweepSteps := flag.Int("sweep", 20, "number of steps in the min confidence sweep")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "concurrent detections")
+	reportFormat := flag.String("report", "markdown", "report format: "+strings.Join(evaluation.ReportFormats, ", "))
+	out := flag.String("out", "", "report file (default stdout)")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+
+	if *annotations == "" {
+		logger.Fatal("-annotations is required")
+	}
+	iouThresholds, err := parseFloats(*iou)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid -iou")
+	}
+	for _, threshold := range iouThresholds {
+		if err := evaluation.CheckIoU(threshold); err != nil {
+			logger.WithError(err).Fatal("Invalid -iou")
+		}
+	}
+	sizeEdges, err := parseFloats(*sizes)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid -sizes")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	selection := *detectorFlag
+	if selection == "" {
+		selection = cfg.Detection.Backend
+	}
+	detector, configured, err := newScoringDetector(cfg, selection, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize detector")
+	}
+	if *minConfidence < 0 {
+		*minConfidence = float64(configured)
+	}
+
+	loaded, err := evaluation.Load(*format, *annotations)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load annotations")
+	}
+	dataset := evaluation.NewDataset(*images, loaded)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	results := evaluation.Detect(ctx, detector, dataset, *workers)
+	for _, result := range results {
+		if result.Err != nil {
+			logger.WithError(result.Err).WithField("image", result.Image).Warn("Image skipped")
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		logger.WithError(err).Fatal("Evaluation interrupted")
+	}
+
+	report := evaluation.Evaluate(dataset.Annotations, results, evaluation.Options{
+		IoUThresholds: iouThresholds,
+		SizeEdges:     sizeEdges,
+		MinConfidence: float32(*minConfidence),
+		SweepSteps:    *sweepSteps,
+	})
+	report.Detector = models.DetectorInfo{Name: detector.Name(), Version: detector.Version()}
+
+	logger.WithFields(logrus.Fields{
+		"images":   report.Images,
+		"failed":   report.ImagesFailed,
+		"faces":    report.Faces,
+		"duration": time.Since(start).Round(time.Millisecond).String(),
+	}).Info("Evaluation completed")
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to create report file")
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := report.Write(w, *reportFormat); err != nil {
+		logger.WithError(err).Fatal("Failed to write report")
+	}
+}
+
+// newScoringDetector returns the detector selection names, as backend or backend:cascade,
+// with its min confidence at zero so every scored detection is kept for the sweep, along
+// with the min confidence it is configured with
+func newScoringDetector(cfg *config.Config, selection string, logger *logrus.Logger) (services.Detector, float32, error) {
+	pigoCfg, skinCfg := cfg.Pigo, cfg.Skin
+	pigoCfg.MinConfidence, skinCfg.MinConfidence = 0, 0
+
+	pigo, err := services.NewFaceDetector(pigoCfg, logger)
+	if err != nil {
+		return nil, 0, err
+	}
+	skin := services.NewSkinDetector(skinCfg)
+
+	backend, cascade, _ := strings.Cut(selection, ":")
+	candidates, err := services.NewDetectorRegistry(config.DetectionConfig{Backend: backend}, pigo, skin)
+	if err != nil {
+		return nil, 0, err
+	}
+	detector, err := candidates.Select(models.DetectorSelection{Detector: backend, Cascade: cascade})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if backend == skin.Name() {
+		return detector, cfg.Skin.MinConfidence, nil
+	}
+	return detector, cfg.Pigo.MinConfidence, nil
+}
+
+// parseFloats parses a comma-separated list of numbers
+func parseFloats(list string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
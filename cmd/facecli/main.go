@@ -0,0 +1,519 @@
+// Command facecli runs the API's face detection on local files and directory trees
+// without the HTTP server. It detects faces, validates selfies, crops faces, anonymizes
+// or annotates images with the detection backends configured like the API through
+// CONFIG_FILE and environment variables, and reports every image as JSON, CSV or NDJSON.
+//
+// Exit codes: 0 when every image was processed (and, for validate, is a valid selfie),
+// 1 on configuration or output errors, 2 on usage errors, 3 when some image could not be
+// processed and 4 when some image is not a valid selfie.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/services"
+)
+
+const (
+	exitFailed  = 3
+	exitInvalid = 4
+)
+
+// commands describes each command for the usage message
+var commands = map[string]string{
+	"detect":    "report the faces found in each image",
+	"validate":  "check each image is a valid selfie",
+	"crop":      "write each face to its own image in -output-dir",
+	"anonymize": "write each image with its faces pixelated or blurred to -output-dir",
+	"annotate":  "write each image with its faces circled to -output-dir",
+}
+
+// writesImages reports whether a command writes images to the output directory
+func writesImages(command string) bool {
+	return command == "crop" || command == "anonymize" || command == "annotate"
+}
+
+func main() {
+	flags := flag.NewFlagSet("facecli", flag.ExitOnError)
+	flags.Usage = func() { usage(flags) }
+
+	detectorFlag := flags.String("detector", "", "backend, optionally with a cascade as backend:cascade (default detection.backend)")
+	profile := flags.String("profile", "", "detection profile from detection.profiles, instead of -detector")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "concurrent images")
+	include := flags.String("include", "*.jpg,*.jpeg,*.png,*.JPG,*.JPEG,*.PNG", "comma-separated globs of files to process in directories")
+	exclude := flags.String("exclude", "", "comma-separated globs of files and directories to skip in directories")
+	format := flags.String("format", "json", "output format: "+strings.Join(outputFormats, ", "))
+	out := flags.String("out", "", "output file (default stdout)")
+	outputDir := flags.String("output-dir", "", "directory images are written to by crop, anonymize and annotate")
+	minFaces := flags.Int("min-faces", 1, "validate: minimum number of faces")
+	maxFaces := flags.Int("max-faces", 1, "validate: maximum number of faces")
+	margin := flags.Float64("margin", 0.1, "crop, anonymize: grow face boxes by this fraction of their size on every side")
+	mode := flags.String("mode", "pixelate", "anonymize: "+strings.Join(services.AnonymizeModes, ", "))
+	strength := flags.Int("strength", 0, "anonymize: block size or blur radius in pixels (default scaled to each face)")
+	circleColor := flags.String("color", "red", "annotate: circle color")
+	lineWidth := flags.Int("line-width", 3, "annotate: circle line width")
+
+	if len(os.Args) < 2 || commands[os.Args[1]] == "" {
+		usage(flags)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	flags.Parse(os.Args[2:])
+	paths := flags.Args()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+
+	if len(paths) == 0 {
+		usageError(flags, "no files or directories given")
+	}
+	if writesImages(command) && *outputDir == "" {
+		usageError(flags, "-output-dir is required by "+command)
+	}
+	if !contains(outputFormats, *format) {
+		usageError(flags, fmt.Sprintf("unknown -format %q", *format))
+	}
+	if command == "anonymize" && !contains(services.AnonymizeModes, *mode) {
+		usageError(flags, fmt.Sprintf("unknown -mode %q", *mode))
+	}
+	circle, err := services.ParseColor(*circleColor)
+	if err != nil {
+		usageError(flags, fmt.Sprintf("invalid -color: %v", err))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	backend, cascade, _ := strings.Cut(*detectorFlag, ":")
+	selection := models.DetectorSelection{Detector: backend, Cascade: cascade, Profile: *profile}
+	detector, err := newDetector(cfg, selection, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize detector")
+	}
+
+	p := &processor{
+		command:    command,
+		detector:   detector,
+		info:       models.DetectorInfo{Name: detector.Name(), Version: detector.Version(), Profile: *profile},
+		downloader: services.NewImageDownloader(cfg.Limits, logger),
+		minFaces:   *minFaces,
+		maxFaces:   *maxFaces,
+		margin:     *margin,
+		circle:     services.CircleOptions{Color: circle, LineWidth: *lineWidth},
+		anonymize:  services.AnonymizeOptions{Mode: *mode, Strength: *strength, Margin: *margin},
+		outputDir:  *outputDir,
+		written:    make(map[string]string),
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to create output file")
+		}
+		defer f.Close()
+		w = f
+	}
+	output := newOutput(*format, w, p.info)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	inputs := make(chan input)
+	go func() {
+		defer close(inputs)
+		walk(ctx, paths, splitGlobs(*include), splitGlobs(*exclude), inputs, logger)
+	}()
+
+	var summary summary
+	for rec := range p.run(ctx, inputs, *workers) {
+		summary.add(rec)
+		if rec.Error != "" {
+			logger.WithFields(logrus.Fields{"path": rec.Path, "error": rec.Error}).Warn("Image failed")
+		}
+		if err := output.Write(rec); err != nil {
+			logger.WithError(err).Fatal("Failed to write output")
+		}
+	}
+	if err := output.Close(summary); err != nil {
+		logger.WithError(err).Fatal("Failed to write output")
+	}
+	if err := ctx.Err(); err != nil {
+		logger.WithError(err).Fatal("Processing interrupted")
+	}
+
+	fields := logrus.Fields{
+		"command":  command,
+		"images":   summary.Images,
+		"failed":   summary.Failed,
+		"faces":    summary.Faces,
+		"duration": time.Since(start).Round(time.Millisecond).String(),
+	}
+	if command == "validate" {
+		fields["invalid"] = summary.Invalid
+	}
+	logger.WithFields(fields).Info("Processing completed")
+
+	switch {
+	case summary.Failed > 0:
+		os.Exit(exitFailed)
+	case summary.Invalid > 0:
+		os.Exit(exitInvalid)
+	}
+}
+
+// usage prints the commands and flags
+func usage(flags *flag.FlagSet) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(flags.Output(), "Usage: facecli <command> [flags] <file or directory>...\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(flags.Output(), "  %-10s %s\n", name, commands[name])
+	}
+	fmt.Fprintf(flags.Output(), "\nFlags:\n")
+	flags.PrintDefaults()
+}
+
+// usageError reports a usage error and exits with status 2, like flag parsing errors
+func usageError(flags *flag.FlagSet, message string) {
+	fmt.Fprintf(flags.Output(), "facecli: %s\n", message)
+	usage(flags)
+	os.Exit(2)
+}
+
+// newDetector builds the detection backends like the API and returns the selected one
+func newDetector(cfg *config.Config, selection models.DetectorSelection, logger *logrus.Logger) (services.Detector, error) {
+	faceDetector, err := services.NewFaceDetector(cfg.Pigo, logger)
+	if err != nil {
+		return nil, err
+	}
+	skinDetector := services.NewSkinDetector(cfg.Skin)
+
+	detectors, err := services.NewDetectorRegistry(cfg.Detection, faceDetector, skinDetector)
+	if err != nil {
+		return nil, err
+	}
+	return detectors.Select(selection)
+}
+
+// input is an image file to process
+type input struct {
+	Path string
+	// Rel is the path output images are written to, relative to the output directory
+	Rel string
+}
+
+// walk sends every file named in paths and every file below the directories in paths
+// that matches include and not exclude. Globs match a file's base name or its path
+// relative to the directory given. With more than one path, output paths keep the path
+// given so inputs from different roots don't share one.
+func walk(ctx context.Context, paths, include, exclude []string, inputs chan<- input, logger *logrus.Logger) {
+	send := func(in input) bool {
+		select {
+		case inputs <- in:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, root := range paths {
+		var prefix string
+		if len(paths) > 1 {
+			prefix = rootPrefix(root)
+		}
+
+		// Paths that can't be read are sent too, so they're reported as failed images
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			rel := filepath.Base(root)
+			if prefix != "" {
+				rel = prefix
+			}
+			if !send(input{Path: root, Rel: rel}) {
+				return
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				logger.WithError(err).WithField("path", path).Warn("Directory skipped")
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil || rel == "." {
+				return nil
+			}
+			if matchAny(exclude, rel) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() || !entry.Type().IsRegular() || !matchAny(include, rel) {
+				return nil
+			}
+			if !send(input{Path: path, Rel: filepath.Join(prefix, rel)}) {
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rootPrefix returns root as a relative path that stays below the output directory, with
+// any volume, leading separators and leading parent directory elements removed
+func rootPrefix(root string) string {
+	clean := filepath.Clean(root)
+	clean = strings.TrimPrefix(clean, filepath.VolumeName(clean))
+	clean = strings.TrimLeft(clean, `/\`)
+	for clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		clean = strings.TrimLeft(strings.TrimPrefix(clean, ".."), `/\`)
+	}
+	if clean == "." {
+		return ""
+	}
+	return clean
+}
+
+// matchAny reports whether the base name or slash-separated path rel matches any glob
+func matchAny(globs []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	base := filepath.Base(rel)
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGlobs splits a comma-separated list of globs
+func splitGlobs(list string) []string {
+	var globs []string
+	for _, glob := range strings.Split(list, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+	return globs
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// processor runs one command on images
+type processor struct {
+	command    string
+	detector   services.Detector
+	info       models.DetectorInfo
+	downloader *services.ImageDownloader
+	minFaces   int
+	maxFaces   int
+	margin     float64
+	circle     services.CircleOptions
+	anonymize  services.AnonymizeOptions
+	outputDir  string
+
+	mu sync.Mutex
+	// written maps each output path claimed so far to the input writing it
+	written map[string]string
+}
+
+// run processes inputs on at most workers goroutines and returns their records in the
+// order they complete
+func (p *processor) run(ctx context.Context, inputs <-chan input, workers int) <-chan record {
+	records := make(chan record)
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range inputs {
+				records <- p.process(ctx, in)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+	return records
+}
+
+// process runs the command on one image. Failures are recorded rather than returned.
+func (p *processor) process(ctx context.Context, in input) record {
+	start := time.Now()
+	rec := record{Path: in.Path, Faces: []faceRecord{}}
+	if err := p.processImage(ctx, in, &rec); err != nil {
+		rec.Error = err.Error()
+	}
+	rec.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return rec
+}
+
+// processImage loads and detects faces in an image and applies the command to them
+func (p *processor) processImage(ctx context.Context, in input, rec *record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(in.Path)
+	if err != nil {
+		return err
+	}
+	data, err := p.downloader.ReadImage(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	img, metadata, err := p.downloader.DecodeImage(ctx, data)
+	if err != nil {
+		return err
+	}
+	rec.Width, rec.Height = metadata.Width, metadata.Height
+
+	faces, err := p.detector.DetectFaces(img)
+	if err != nil {
+		return fmt.Errorf("detection failed: %w", err)
+	}
+	rec.Count = len(faces)
+	rec.Faces = make([]faceRecord, len(faces))
+	for i, face := range faces {
+		rec.Faces[i].Face = face
+	}
+
+	switch p.command {
+	case "validate":
+		selfie := services.ValidateSelfie(faces, p.minFaces, p.maxFaces, p.detector.Capabilities().LowConfidence)
+		selfie.Detector = p.info
+		rec.Selfie = &selfie
+	case "crop":
+		for i, face := range faces {
+			path, err := p.outputPath(in, fmt.Sprintf("_face%d", i+1))
+			if err != nil {
+				return err
+			}
+			if err := writeImage(path, services.CropFace(img, face, p.margin)); err != nil {
+				return err
+			}
+			rec.Faces[i].Output = path
+		}
+	case "anonymize":
+		anonymized, err := services.AnonymizeFaces(img, faces, p.anonymize)
+		if err != nil {
+			return err
+		}
+		return p.writeOutput(in, anonymized, rec)
+	case "annotate":
+		return p.writeOutput(in, services.AnnotateFaces(img, faces, p.circle), rec)
+	}
+	return nil
+}
+
+// writeOutput writes img to the output path of an input and records it
+func (p *processor) writeOutput(in input, img image.Image, rec *record) error {
+	path, err := p.outputPath(in, "")
+	if err != nil {
+		return err
+	}
+	if err := writeImage(path, img); err != nil {
+		return err
+	}
+	rec.Output = path
+	return nil
+}
+
+// outputPath returns the path below the output directory that mirrors an input, with
+// suffix added to its name. It refuses to overwrite the input itself or the output of
+// another input.
+func (p *processor) outputPath(in input, suffix string) (string, error) {
+	ext := filepath.Ext(in.Rel)
+	path := filepath.Join(p.outputDir, strings.TrimSuffix(in.Rel, ext)+suffix+ext)
+
+	source, err := filepath.Abs(in.Path)
+	if err != nil {
+		return "", err
+	}
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if source == target {
+		return "", errors.New("output would overwrite the input image")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if other, ok := p.written[target]; ok && other != source {
+		return "", fmt.Errorf("output %s is already written for %s", path, other)
+	}
+	p.written[target] = source
+	return path, nil
+}
+
+// writeImage encodes img to path as PNG or JPEG by its extension, creating directories
+// as needed
+func writeImage(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"face-recognition-api/internal/models"
+)
+
+// outputFormats lists the formats records can be written in
+var outputFormats = []string{"json", "csv", "ndjson"}
+
+// record is the outcome of processing one image
+type record struct {
+	Path       string                           `json:"path"`
+	Width      int                              `json:"width,omitempty"`
+	Height     int                              `json:"height,omitempty"`
+	Faces      []faceRecord                     `json:"faces"`
+	Count      int                              `json:"count"`
+	Selfie     *models.SelfieValidationResponse `json:"selfie,omitempty"`
+	Output     string                           `json:"output,omitempty"`
+	DurationMs float64                          `json:"duration_ms"`
+	Error      string                           `json:"error,omitempty"`
+}
+
+// faceRecord is a detected face, with the image it was cropped to if any
+type faceRecord struct {
+	models.Face
+	Output string `json:"output,omitempty"`
+}
+
+// summary counts the outcomes of every processed image
+type summary struct {
+	Images  int `json:"images"`
+	Failed  int `json:"failed"`
+	Faces   int `json:"faces"`
+	Invalid int `json:"invalid"`
+}
+
+// add counts a record
+func (s *summary) add(rec record) {
+	s.Images++
+	s.Faces += rec.Count
+	if rec.Error != "" {
+		s.Failed++
+	}
+	if rec.Selfie != nil && !rec.Selfie.IsValid {
+		s.Invalid++
+	}
+}
+
+// output writes records as they complete
+type output interface {
+	Write(rec record) error
+	// Close writes anything buffered, along with the summary if the format has one
+	Close(s summary) error
+}
+
+// newOutput returns an output writing format, one of outputFormats, to w
+func newOutput(format string, w io.Writer, detector models.DetectorInfo) output {
+	switch format {
+	case "csv":
+		return &csvOutput{w: csv.NewWriter(w)}
+	case "ndjson":
+		return &ndjsonOutput{encoder: json.NewEncoder(w)}
+	default:
+		return &jsonOutput{w: w, detector: detector}
+	}
+}
+
+// jsonOutput writes one document holding every record, sorted by path, and the summary
+type jsonOutput struct {
+	w        io.Writer
+	detector models.DetectorInfo
+	records  []record
+}
+
+func (o *jsonOutput) Write(rec record) error {
+	o.records = append(o.records, rec)
+	return nil
+}
+
+func (o *jsonOutput) Close(s summary) error {
+	sort.Slice(o.records, func(i, j int) bool { return o.records[i].Path < o.records[j].Path })
+	encoder := json.NewEncoder(o.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Detector models.DetectorInfo `json:"detector"`
+		Summary  summary             `json:"summary"`
+		Images   []record            `json:"images"`
+	}{o.detector, s, o.records})
+}
+
+// ndjsonOutput writes each record on its own line as soon as it completes
+type ndjsonOutput struct {
+	encoder *json.Encoder
+}
+
+func (o *ndjsonOutput) Write(rec record) error {
+	return o.encoder.Encode(rec)
+}
+
+func (o *ndjsonOutput) Close(summary) error {
+	return nil
+}
+
+// csvOutput writes a row per detected face, or a single row for an image without faces,
+// as soon as each image completes
+type csvOutput struct {
+	w      *csv.Writer
+	header bool
+}
+
+var csvHeader = []string{
+	"path", "image_width", "image_height", "face_count", "face", "x", "y", "width", "height",
+	"confidence", "valid", "issue_codes", "output", "duration_ms", "error",
+}
+
+func (o *csvOutput) Write(rec record) error {
+	if !o.header {
+		o.header = true
+		if err := o.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	var valid, issues string
+	if rec.Selfie != nil {
+		valid = strconv.FormatBool(rec.Selfie.IsValid)
+		issues = strings.Join(rec.Selfie.IssueCodes, ";")
+	}
+	row := func(index, x, y, width, height, confidence, output string) []string {
+		return []string{
+			rec.Path, itoa(rec.Width), itoa(rec.Height), strconv.Itoa(rec.Count), index, x, y, width, height,
+			confidence, valid, issues, output, strconv.FormatFloat(rec.DurationMs, 'f', 3, 64), rec.Error,
+		}
+	}
+
+	if len(rec.Faces) == 0 {
+		if err := o.w.Write(row("", "", "", "", "", "", rec.Output)); err != nil {
+			return err
+		}
+	}
+	for i, face := range rec.Faces {
+		output := face.Output
+		if output == "" {
+			output = rec.Output
+		}
+		err := o.w.Write(row(strconv.Itoa(i+1), strconv.Itoa(face.X), strconv.Itoa(face.Y),
+			strconv.Itoa(face.Width), strconv.Itoa(face.Height), fmt.Sprintf("%.4f", face.Confidence), output))
+		if err != nil {
+			return err
+		}
+	}
+	// Flushed per image so rows stream out as images complete
+	o.w.Flush()
+	return o.w.Error()
+}
+
+func (o *csvOutput) Close(summary) error {
+	if !o.header {
+		if err := o.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// itoa formats n, leaving zero empty for images that failed before being decoded
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
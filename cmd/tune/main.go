@@ -0,0 +1,402 @@
+// Command tune searches pigo parameters for the best accuracy on a labeled dataset. It
+// evaluates a grid or random sample of MinSize, ShiftFactor, ScaleFactor and IoUThreshold
+// combinations, picks each one's MinConfidence from a sweep, reports the Pareto frontier
+// of accuracy against latency and writes the best configuration as a config file section.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/evaluation"
+	"face-recognition-api/internal/services"
+)
+
+func main() {
+	format := flag.String("format", "json", "annotation format: "+strings.Join(evaluation.Formats, ", "))
+	annotations := flag.String("annotations", "", "annotation file, or a glob matching several (e.g. FDDB folds)")
+	images := flag.String("images", ".", "directory annotation image paths are relative to")
+	cascade := flag.String("cascade", "", "cascade to tune (default the configured fallback)")
+	search := flag.String("search", "grid", "search strategy: grid or random")
+	trials := flag.Int("trials", 50, "random search trials")
+	seed := flag.Int64("seed", 1, "random search seed")
+	minSize := flag.String("min-size", "", "min_size values as a,b,c or, for random search, a lo:hi range (default configured)")
+	shiftFactor := flag.String("shift-factor", "", "shift_factor values or range (default configured)")
+	scaleFactor := flag.String("scale-factor", "", "scale_factor values or range (default configured)")
+	iouThreshold := flag.String("iou-threshold", "", "iou_threshold values or range, for clustering detections (default configured)")
+	minConfidence := flag.String("min-confidence", "", "min_confidence values to choose from (default a sweep over the scores)")
+	sweepSteps := flag.Int("sweep", 20, "steps of the min confidence sweep when -min-confidence is not set")
+	matchIoU := flag.Float64("match-iou", 0.5, "IoU at which a detection counts as finding a face")
+	objective := flag.String("objective", "f1", "accuracy to maximize: "+strings.Join(evaluation.Objectives, ", "))
+	latency := flag.String("latency", "mean", "latency to minimize: mean, p95 or p99")
+	maxLatency := flag.Float64("max-latency", 0, "latency budget in milliseconds for the best configuration (0 for none)")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "concurrent detections; 1 gives the steadiest latencies")
+	preload := flag.Bool("preload", true, "decode every image once up front instead of on every trial")
+	reportFormat := flag.String("report", "markdown", "report format: "+strings.Join(evaluation.ReportFormats, ", "))
+	out := flag.String("out", "", "report file (default stdout)")
+	emit := flag.String("emit", "pigo", "section to write the best configuration as: pigo, shadow or none")
+	emitFile := flag.String("emit-file", "tuned.yaml", "file the best configuration is written to")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+
+	if *annotations == "" {
+		logger.Fatal("-annotations is required")
+	}
+	if !oneOf(*objective, evaluation.Objectives...) {
+		logger.Fatalf("-objective must be one of %q", evaluation.Objectives)
+	}
+	if !oneOf(*latency, "mean", "p95", "p99") {
+		logger.Fatal("-latency must be mean, p95 or p99")
+	}
+	if !oneOf(*emit, "pigo", "shadow", "none") {
+		logger.Fatal("-emit must be pigo, shadow or none")
+	}
+	if err := evaluation.CheckIoU(*matchIoU); err != nil {
+		logger.WithError(err).Fatal("Invalid -match-iou")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	space, err := newSearchSpace(cfg.Pigo, *minSize, *shiftFactor, *scaleFactor, *iouThreshold)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid search space")
+	}
+	var candidates []config.PigoConfig
+	switch *search {
+	case "grid":
+		candidates, err = space.grid()
+	case "random":
+		candidates = space.random(rand.New(rand.NewSource(*seed)), *trials)
+	default:
+		err = fmt.Errorf("unknown search strategy %q, want grid or random", *search)
+	}
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid search")
+	}
+
+	var sweep []float32
+	if *minConfidence != "" {
+		values, err := parseList(*minConfidence)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid -min-confidence")
+		}
+		for _, v := range values {
+			sweep = append(sweep, float32(v))
+		}
+	}
+
+	// Every trial runs at min confidence zero; the sweep picks it afterwards
+	scoring := cfg.Pigo
+	scoring.MinConfidence = 0
+	faceDetector, err := services.NewFaceDetector(scoring, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize face detector")
+	}
+	if *cascade == "" {
+		*cascade = faceDetector.Fallback()
+	}
+	detector, err := faceDetector.WithCascade(*cascade)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to select cascade")
+	}
+	// The pigo section applies to every cascade, and profiles can't carry parameters, so
+	// parameters tuned on another cascade would retune the fallback serving default traffic
+	if *emit == "pigo" && *cascade != faceDetector.Fallback() {
+		logger.WithFields(logrus.Fields{
+			"cascade":  *cascade,
+			"fallback": faceDetector.Fallback(),
+		}).Fatal("-emit pigo would apply parameters tuned on this cascade to the fallback cascade; use -emit shadow or -emit none")
+	}
+
+	loaded, err := evaluation.Load(*format, *annotations)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load annotations")
+	}
+	dataset := evaluation.NewDataset(*images, loaded)
+	if *preload {
+		dataset.Preload(*workers)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report := &evaluation.TuneReport{
+		Dataset:   *annotations,
+		Cascade:   *cascade,
+		Objective: *objective,
+		MatchIoU:  *matchIoU,
+		Latency:   *latency,
+	}
+
+	for i, candidate := range candidates {
+		if err := candidate.Validate(); err != nil {
+			logger.WithError(err).WithField("trial", i+1).Warn("Trial skipped")
+			continue
+		}
+		run := candidate
+		run.MinConfidence = 0
+		if err := faceDetector.SetConfig(run); err != nil {
+			logger.WithError(err).Fatal("Failed to configure face detector")
+		}
+
+		results := evaluation.Detect(ctx, detector, dataset, *workers)
+		if err := ctx.Err(); err != nil {
+			logger.WithError(err).Warn("Search interrupted, reporting finished trials")
+			break
+		}
+		if len(report.Trials) == 0 {
+			for _, result := range results {
+				if result.Err != nil {
+					logger.WithError(result.Err).WithField("image", result.Image).Warn("Image skipped")
+				}
+			}
+		}
+
+		evaluated := evaluation.Evaluate(dataset.Annotations, results, evaluation.Options{
+			IoUThresholds: []float64{*matchIoU},
+			Sweep:         sweep,
+			SweepSteps:    *sweepSteps,
+		})
+		report.Images, report.ImagesFailed, report.Faces = evaluated.Images, evaluated.ImagesFailed, evaluated.Faces
+		trial := evaluation.NewTrial(candidate, evaluated, *objective, *latency)
+		report.Trials = append(report.Trials, trial)
+
+		logger.WithFields(logrus.Fields{
+			"trial":      fmt.Sprintf("%d/%d", i+1, len(candidates)),
+			"score":      trial.Score,
+			"latency_ms": trial.LatencyMs,
+		}).Info("Trial completed")
+	}
+	if len(report.Trials) == 0 {
+		logger.Fatal("No trial completed")
+	}
+
+	evaluation.MarkPareto(report.Trials)
+	best, ok := evaluation.Best(report.Trials, *maxLatency)
+	if !ok {
+		logger.WithField("max_latency_ms", *maxLatency).Warn("No trial is within the latency budget")
+	} else {
+		report.Best = &best
+	}
+
+	if err := writeReport(report, *out, *reportFormat); err != nil {
+		logger.WithError(err).Fatal("Failed to write report")
+	}
+
+	if report.Best != nil && *emit != "none" {
+		if err := writeConfig(*emitFile, *emit, cfg, report); err != nil {
+			logger.WithError(err).Fatal("Failed to write configuration")
+		}
+		logger.WithField("file", *emitFile).Info("Best configuration written")
+	}
+}
+
+// searchSpace holds the values each searched pigo parameter takes
+type searchSpace struct {
+	base                                            config.PigoConfig
+	minSize, shiftFactor, scaleFactor, iouThreshold param
+}
+
+// newSearchSpace parses the parameter specs; parameters without one keep their base value
+func newSearchSpace(base config.PigoConfig, minSize, shiftFactor, scaleFactor, iouThreshold string) (*searchSpace, error) {
+	s := &searchSpace{base: base}
+	specs := []struct {
+		name string
+		spec string
+		base float64
+		dst  *param
+	}{
+		{"min-size", minSize, float64(base.MinSize), &s.minSize},
+		{"shift-factor", shiftFactor, float64(base.ShiftFactor), &s.shiftFactor},
+		{"scale-factor", scaleFactor, float64(base.ScaleFactor), &s.scaleFactor},
+		{"iou-threshold", iouThreshold, float64(base.IoUThreshold), &s.iouThreshold},
+	}
+	for _, p := range specs {
+		parsed, err := parseParam(p.spec, p.base)
+		if err != nil {
+			return nil, fmt.Errorf("-%s: %w", p.name, err)
+		}
+		*p.dst = parsed
+	}
+	return s, nil
+}
+
+// grid returns every combination of the listed values
+func (s *searchSpace) grid() ([]config.PigoConfig, error) {
+	for _, p := range []param{s.minSize, s.shiftFactor, s.scaleFactor, s.iouThreshold} {
+		if p.ranged {
+			return nil, fmt.Errorf("grid search needs value lists, not lo:hi ranges")
+		}
+	}
+
+	var configs []config.PigoConfig
+	for _, minSize := range s.minSize.values {
+		for _, shift := range s.shiftFactor.values {
+			for _, scale := range s.scaleFactor.values {
+				for _, iou := range s.iouThreshold.values {
+					configs = append(configs, s.config(minSize, shift, scale, iou))
+				}
+			}
+		}
+	}
+	return configs, nil
+}
+
+// random returns n combinations drawn independently for each parameter
+func (s *searchSpace) random(rng *rand.Rand, n int) []config.PigoConfig {
+	configs := make([]config.PigoConfig, n)
+	for i := range configs {
+		configs[i] = s.config(
+			math.Round(s.minSize.sample(rng)),
+			s.shiftFactor.sample(rng),
+			s.scaleFactor.sample(rng),
+			s.iouThreshold.sample(rng),
+		)
+	}
+	return configs
+}
+
+// config returns the base configuration with the searched parameters replaced
+func (s *searchSpace) config(minSize, shift, scale, iou float64) config.PigoConfig {
+	c := s.base
+	c.MinSize = int(minSize)
+	c.ShiftFactor = float32(shift)
+	c.ScaleFactor = float32(scale)
+	c.IoUThreshold = float32(iou)
+	return c
+}
+
+// param is the values a searched parameter takes: a list, or a lo:hi range sampled
+// uniformly by random search
+type param struct {
+	values []float64
+	lo, hi float64
+	ranged bool
+}
+
+// parseParam parses "a,b,c" or "lo:hi"; an empty spec is the base value alone
+func parseParam(spec string, base float64) (param, error) {
+	if spec == "" {
+		return param{values: []float64{base}}, nil
+	}
+	if lo, hi, found := strings.Cut(spec, ":"); found {
+		l, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+		if err != nil {
+			return param{}, fmt.Errorf("%q is not a number", lo)
+		}
+		h, err := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+		if err != nil {
+			return param{}, fmt.Errorf("%q is not a number", hi)
+		}
+		if h < l {
+			return param{}, fmt.Errorf("range %s is empty", spec)
+		}
+		return param{lo: l, hi: h, ranged: true}, nil
+	}
+
+	values, err := parseList(spec)
+	if err != nil {
+		return param{}, err
+	}
+	if len(values) == 0 {
+		return param{}, fmt.Errorf("no values in %q", spec)
+	}
+	return param{values: values}, nil
+}
+
+// sample draws a value of the parameter
+func (p param) sample(rng *rand.Rand) float64 {
+	if p.ranged {
+		return p.lo + rng.Float64()*(p.hi-p.lo)
+	}
+	return p.values[rng.Intn(len(p.values))]
+}
+
+// parseList parses a comma-separated list of numbers
+func parseList(list string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// writeReport writes the search report to path, or stdout when path is empty
+func writeReport(report *evaluation.TuneReport, path, format string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return report.Write(w, format)
+}
+
+// writeConfig writes the best configuration as a pigo section, ready to replace the one
+// in the config file, or as a shadow section to try it on live traffic first
+func writeConfig(path, section string, cfg *config.Config, report *evaluation.TuneReport) error {
+	var doc interface{}
+	switch section {
+	case "pigo":
+		doc = struct {
+			Pigo config.PigoConfig `yaml:"pigo"`
+		}{report.Best.Pigo}
+	case "shadow":
+		shadow := cfg.Shadow
+		shadow.Enabled = true
+		shadow.Detector = "pigo:" + report.Cascade
+		shadow.Pigo = report.Best.Pigo
+		doc = struct {
+			Shadow config.ShadowConfig `yaml:"shadow"`
+		}{shadow}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	header := fmt.Sprintf("# Tuned on %s: %s %.3f, precision %.3f, recall %.3f, %s latency %.1f ms\n",
+		report.Dataset, report.Objective, report.Best.Score, report.Best.Precision, report.Best.Recall,
+		report.Latency, report.Best.LatencyMs)
+	return os.WriteFile(path, append([]byte(header), buf.Bytes()...), 0o644)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
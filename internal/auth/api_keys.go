@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"face-recognition-api/internal/config"
+)
+
+// Authentication errors
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidKey         = errors.New("invalid API key")
+	ErrKeyExpired         = errors.New("API key expired")
+)
+
+// APIKey is one configured key. Only the SHA-256 hash of the key is stored; keys are random
+// high-entropy tokens, so a fast hash is enough to keep them from leaking through config.
+type APIKey struct {
+	ID        string            `json:"id"`
+	Hash      string            `json:"hash"`
+	Scopes    []Scope           `json:"scopes"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// KeyStore authenticates API keys against their configured hashes
+type KeyStore struct {
+	keys map[string]*APIKey
+}
+
+// NewKeyStore builds a key store from the inline and file-based keys in cfg
+func NewKeyStore(cfg config.AuthConfig) (*KeyStore, error) {
+	var keys []*APIKey
+
+	if cfg.APIKeys != "" {
+		if err := json.Unmarshal([]byte(cfg.APIKeys), &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse AUTH_API_KEYS: %w", err)
+		}
+	}
+
+	if cfg.APIKeysFile != "" {
+		data, err := os.ReadFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys file: %w", err)
+		}
+		var fileKeys []*APIKey
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	store := &KeyStore{keys: make(map[string]*APIKey, len(keys))}
+	ids := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate API key id %q", key.ID)
+		}
+		ids[key.ID] = true
+
+		hash := strings.ToLower(strings.TrimPrefix(key.Hash, "sha256:"))
+		if _, ok := store.keys[hash]; ok {
+			return nil, fmt.Errorf("API key %q reuses the hash of another key", key.ID)
+		}
+		store.keys[hash] = key
+	}
+
+	return store, nil
+}
+
+// validateKey checks one configured key for missing or malformed fields
+func validateKey(key *APIKey) error {
+	if key.ID == "" {
+		return errors.New("API key without id")
+	}
+
+	hash := strings.TrimPrefix(key.Hash, "sha256:")
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("API key %q: hash must be a hex SHA-256 digest", key.ID)
+	}
+
+	if len(key.Scopes) == 0 {
+		return fmt.Errorf("API key %q has no scopes", key.ID)
+	}
+	for _, scope := range key.Scopes {
+		if !validScope(scope) {
+			return fmt.Errorf("API key %q: unknown scope %q", key.ID, scope)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of configured keys
+func (s *KeyStore) Len() int {
+	return len(s.keys)
+}
+
+// Authenticate resolves a presented API key to the identity it was issued to
+func (s *KeyStore) Authenticate(presented string, now time.Time) (*Identity, error) {
+	if presented == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	key, ok := s.keys[HashKey(presented)]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, fmt.Errorf("%w: key %q expired at %s", ErrKeyExpired, key.ID, key.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return &Identity{
+		ID:        key.ID,
+		Method:    "api_key",
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		Metadata:  key.Metadata,
+	}, nil
+}
+
+// HashKey returns the hex SHA-256 hash under which a key is stored
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scope grants access to a group of endpoints
+type Scope string
+
+// Supported scopes; ScopeAdmin grants every other scope
+const (
+	ScopeDetect   Scope = "detect"
+	ScopeValidate Scope = "validate"
+	ScopeVisual   Scope = "visual"
+	ScopeAdmin    Scope = "admin"
+)
+
+// validScope reports whether s is a known scope
+func validScope(s Scope) bool {
+	switch s {
+	case ScopeDetect, ScopeValidate, ScopeVisual, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Identity describes an authenticated caller
+type Identity struct {
+	ID        string
+	Method    string
+	Scopes    []Scope
+	ExpiresAt *time.Time
+	Metadata  map[string]string
+}
+
+// HasScope reports whether the identity was granted scope, directly or through admin
+func (i *Identity) HasScope(scope Scope) bool {
+	for _, s := range i.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal returns a key that identifies the caller across credential sources: API keys
+// as key:<id> and tokens as jwt:<issuer>:<subject>, so a token subject can never collide
+// with an API key ID or a subject of another issuer
+func (i *Identity) Principal() string {
+	if i.Method == "jwt" {
+		return "jwt:" + i.Metadata["issuer"] + ":" + i.ID
+	}
+	return "key:" + i.ID
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the caller identity
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the caller identity carried by ctx, or nil for anonymous requests
+func FromContext(ctx context.Context) *Identity {
+	if ctx == nil {
+		return nil
+	}
+	identity, _ := ctx.Value(contextKey{}).(*Identity)
+	return identity
+}
+
+// LogHook adds a client_id field to every log entry whose context carries an identity.
+// Entries pick up the context through logger.WithContext(ctx).
+type LogHook struct{}
+
+// Levels implements logrus.Hook
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if identity := FromContext(entry.Context); identity != nil {
+		entry.Data["client_id"] = identity.ID
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/metrics"
+)
+
+const (
+	// maxJWKSSize bounds the size of a fetched key set
+	maxJWKSSize = 1 << 20
+
+	// jwksMinRefresh limits how often unknown key IDs can trigger a reload
+	jwksMinRefresh = 30 * time.Second
+)
+
+// keySet caches the signing keys of one issuer. Keys are reloaded when the cache is older
+// than the refresh interval, or when a token names an unknown key ID after a rotation.
+// A failed reload keeps serving the previously loaded keys.
+type keySet struct {
+	issuer  string
+	url     string
+	file    string
+	refresh time.Duration
+	client  *http.Client
+	logger  *logrus.Logger
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	loadedAt  time.Time
+	attemptAt time.Time
+	// loading is closed when the reload in flight completes, nil when there is none
+	loading chan struct{}
+}
+
+// stats describes the cached keys
+func (s *keySet) stats() KeySetStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := KeySetStats{Issuer: s.issuer, Keys: len(s.keys)}
+	if !s.loadedAt.IsZero() {
+		loadedAt := s.loadedAt
+		stats.LoadedAt = &loadedAt
+	}
+	return stats
+}
+
+// key returns the verification key for kid; an empty kid selects the only key of the set.
+// The key set is fetched without holding s.mu: while one caller reloads it, callers the
+// cached keys can serve use them, and only callers whose key is missing wait.
+func (s *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	_, known := s.keys[kid]
+	stale := now.Sub(s.loadedAt) >= s.refresh
+	switch {
+	case s.loading == nil && (stale || (!known && kid != "")) && now.Sub(s.attemptAt) >= jwksMinRefresh:
+		s.loading = make(chan struct{})
+		s.mu.Unlock()
+		// Don't let one caller's cancellation abort a reload that every caller relies on
+		if err := s.load(context.WithoutCancel(ctx)); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("issuer", s.issuer).Warn("Failed to refresh JWKS, using cached keys")
+		}
+		s.mu.Lock()
+	case s.loading != nil && s.lookup(kid) == nil:
+		loading := s.loading
+		s.mu.Unlock()
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+
+	key := s.lookup(kid)
+	if key == nil {
+		return nil, fmt.Errorf("no signing key %q for issuer %s", kid, s.issuer)
+	}
+	return key, nil
+}
+
+// lookup returns the cached key for kid, or nil when there is none. Callers hold s.mu.
+func (s *keySet) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+// load reads and parses the key set, then replaces the cached keys on success and wakes
+// callers waiting for the reload. Callers don't hold s.mu, which is only taken to record
+// the result.
+func (s *keySet) load(ctx context.Context) error {
+	attemptAt := time.Now()
+	data, err := s.read(ctx)
+	var keys map[string]crypto.PublicKey
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attemptAt = attemptAt
+	if s.loading != nil {
+		close(s.loading)
+		s.loading = nil
+	}
+	if err != nil {
+		metrics.JWKSRefreshes.WithLabelValues("error").Inc()
+		return err
+	}
+	s.keys = keys
+	s.loadedAt = attemptAt
+	metrics.JWKSRefreshes.WithLabelValues("success").Inc()
+	return nil
+}
+
+// read returns the raw key set from the configured file or URL
+func (s *keySet) read(ctx context.Context) ([]byte, error) {
+	if s.file != "" {
+		return os.ReadFile(s.file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// jsonWebKey is the subset of RFC 7517 key members needed for signature verification
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes the RSA, EC and Ed25519 signing keys of a JWK set, indexed by key ID.
+// Encryption keys and unsupported key types are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, returning nil for unsupported key types
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+// decodeBigInt decodes a base64url-encoded unsigned big-endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"face-recognition-api/internal/config"
+)
+
+// Bearer token errors
+var (
+	ErrInvalidToken = errors.New("invalid bearer token")
+	ErrTokenExpired = errors.New("bearer token expired")
+)
+
+// signingMethods are the accepted JWT algorithms; "none" and HMAC are never accepted
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Issuer is one trusted token issuer. Tokens must carry one of its audiences and be signed
+// by a key of its JWKS, loaded from a URL or a file.
+type Issuer struct {
+	Issuer    string   `json:"issuer"`
+	Audiences []string `json:"audiences"`
+	JWKSURL   string   `json:"jwks_url,omitempty"`
+	JWKSFile  string   `json:"jwks_file,omitempty"`
+
+	// SubjectClaim names the claim identifying the client (default "sub")
+	SubjectClaim string `json:"subject_claim,omitempty"`
+
+	// ScopeClaim names the claim holding granted values, either a space-separated string or
+	// an array of strings (default "scope")
+	ScopeClaim string `json:"scope_claim,omitempty"`
+
+	// ScopeMap maps claim values to scopes. When empty, claim values that name a scope are
+	// granted directly.
+	ScopeMap map[string][]Scope `json:"scope_map,omitempty"`
+}
+
+// trustedIssuer is a configured issuer along with its cached signing keys
+type trustedIssuer struct {
+	config *Issuer
+	keys   *keySet
+}
+
+// TokenVerifier authenticates JWT bearer tokens from trusted issuers
+type TokenVerifier struct {
+	issuers map[string]*trustedIssuer
+	leeway  time.Duration
+}
+
+// NewTokenVerifier builds a verifier from the inline and file-based issuers in cfg and loads
+// their signing keys. Unreachable JWKS URLs are retried on first use rather than failing startup.
+func NewTokenVerifier(cfg config.AuthConfig, logger *logrus.Logger) (*TokenVerifier, error) {
+	var issuers []*Issuer
+
+	if cfg.JWTIssuers != "" {
+		if err := json.Unmarshal([]byte(cfg.JWTIssuers), &issuers); err != nil {
+			return nil, fmt.Errorf("failed to parse AUTH_JWT_ISSUERS: %w", err)
+		}
+	}
+
+	if cfg.JWTIssuersFile != "" {
+		data, err := os.ReadFile(cfg.JWTIssuersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT issuers file: %w", err)
+		}
+		var fileIssuers []*Issuer
+		if err := json.Unmarshal(data, &fileIssuers); err != nil {
+			return nil, fmt.Errorf("failed to parse JWT issuers file: %w", err)
+		}
+		issuers = append(issuers, fileIssuers...)
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+
+	verifier := &TokenVerifier{
+		issuers: make(map[string]*trustedIssuer, len(issuers)),
+		leeway:  cfg.JWTLeeway,
+	}
+	for _, issuer := range issuers {
+		if err := validateIssuer(issuer); err != nil {
+			return nil, err
+		}
+		if _, ok := verifier.issuers[issuer.Issuer]; ok {
+			return nil, fmt.Errorf("duplicate JWT issuer %q", issuer.Issuer)
+		}
+		if issuer.SubjectClaim == "" {
+			issuer.SubjectClaim = "sub"
+		}
+		if issuer.ScopeClaim == "" {
+			issuer.ScopeClaim = "scope"
+		}
+
+		keys := &keySet{
+			issuer:  issuer.Issuer,
+			url:     issuer.JWKSURL,
+			file:    issuer.JWKSFile,
+			refresh: cfg.JWKSRefreshInterval,
+			client:  client,
+			logger:  logger,
+		}
+		if err := keys.load(context.Background()); err != nil {
+			if keys.file != "" {
+				return nil, fmt.Errorf("JWT issuer %q: %w", issuer.Issuer, err)
+			}
+			logger.WithError(err).WithField("issuer", issuer.Issuer).Warn("Failed to load JWKS, retrying on first use")
+		}
+
+		verifier.issuers[issuer.Issuer] = &trustedIssuer{config: issuer, keys: keys}
+	}
+
+	return verifier, nil
+}
+
+// validateIssuer checks one configured issuer for missing or malformed fields
+func validateIssuer(issuer *Issuer) error {
+	if issuer.Issuer == "" {
+		return errors.New("JWT issuer without issuer")
+	}
+	if len(issuer.Audiences) == 0 {
+		return fmt.Errorf("JWT issuer %q has no audiences", issuer.Issuer)
+	}
+
+	if (issuer.JWKSURL == "") == (issuer.JWKSFile == "") {
+		return fmt.Errorf("JWT issuer %q needs exactly one of jwks_url and jwks_file", issuer.Issuer)
+	}
+	if issuer.JWKSURL != "" {
+		if u, err := url.Parse(issuer.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("JWT issuer %q: jwks_url must be an http or https URL", issuer.Issuer)
+		}
+	}
+
+	for value, scopes := range issuer.ScopeMap {
+		for _, scope := range scopes {
+			if !validScope(scope) {
+				return fmt.Errorf("JWT issuer %q: claim value %q maps to unknown scope %q", issuer.Issuer, value, scope)
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of trusted issuers
+func (v *TokenVerifier) Len() int {
+	return len(v.issuers)
+}
+
+// KeySetStats describes the cached signing keys of one issuer
+type KeySetStats struct {
+	Issuer   string     `json:"issuer"`
+	Keys     int        `json:"keys"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+}
+
+// KeyStats reports the cached signing keys of every trusted issuer
+func (v *TokenVerifier) KeyStats() []KeySetStats {
+	stats := make([]KeySetStats, 0, len(v.issuers))
+	for _, issuer := range v.issuers {
+		stats = append(stats, issuer.keys.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Issuer < stats[j].Issuer })
+	return stats
+}
+
+// Verify checks a bearer token's signature, issuer, audience and validity period, and
+// resolves it to the identity of the client it was issued to
+func (v *TokenVerifier) Verify(ctx context.Context, token string, now time.Time) (*Identity, error) {
+	if token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+
+	var issuer *trustedIssuer
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		iss, _ := t.Claims.GetIssuer()
+		if issuer = v.issuers[iss]; issuer == nil {
+			return nil, fmt.Errorf("untrusted issuer %q", iss)
+		}
+		kid, _ := t.Header["kid"].(string)
+		return issuer.keys.key(ctx, kid)
+	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	cfg := issuer.config
+	audiences, _ := claims.GetAudience()
+	if !intersects(audiences, cfg.Audiences) {
+		return nil, fmt.Errorf("%w: audience %v not accepted by issuer %s", ErrInvalidToken, []string(audiences), cfg.Issuer)
+	}
+
+	subject, _ := claims[cfg.SubjectClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, cfg.SubjectClaim)
+	}
+
+	identity := &Identity{
+		ID:       subject,
+		Method:   "jwt",
+		Scopes:   cfg.scopes(claims[cfg.ScopeClaim]),
+		Metadata: map[string]string{"issuer": cfg.Issuer},
+	}
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		identity.ExpiresAt = &exp.Time
+	}
+	return identity, nil
+}
+
+// scopes maps the values of a scope claim to granted scopes
+func (i *Issuer) scopes(claim interface{}) []Scope {
+	var values []string
+	switch c := claim.(type) {
+	case string:
+		values = strings.Fields(c)
+	case []interface{}:
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var scopes []Scope
+	seen := make(map[Scope]bool)
+	grant := func(scope Scope) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, value := range values {
+		if len(i.ScopeMap) == 0 {
+			if validScope(Scope(value)) {
+				grant(Scope(value))
+			}
+			continue
+		}
+		for _, scope := range i.ScopeMap[value] {
+			grant(scope)
+		}
+	}
+	return scopes
+}
+
+// intersects reports whether a and b share an element
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,108 +1,396 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// FileEnv names the environment variable holding the path of the YAML configuration file
+const FileEnv = "CONFIG_FILE"
+
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig
-	Pigo   PigoConfig
-	Limits LimitsConfig
+	Server    ServerConfig    `yaml:"server"`
+	Detection DetectionConfig `yaml:"detection"`
+	Pigo      PigoConfig      `yaml:"pigo"`
+	Skin      SkinConfig      `yaml:"skin"`
+	Shadow    ShadowConfig    `yaml:"shadow"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Pool      PoolConfig      `yaml:"pool"`
+	Batch     BatchConfig     `yaml:"batch"`
+	Jobs      JobsConfig      `yaml:"jobs"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Health    HealthConfig    `yaml:"health"`
+	Reload    ReloadConfig    `yaml:"reload"`
+	Admin     AdminConfig     `yaml:"admin"`
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	PreStopDelay    time.Duration `yaml:"pre_stop_delay"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Backends lists the names of the detection backends the service provides
+var Backends = []string{"pigo", "skin"}
+
+// DetectionConfig selects detection backends. Backend is used when a request names
+// neither a backend nor a profile; Profiles map profile names to a backend, optionally
+// with a cascade as backend:cascade.
+type DetectionConfig struct {
+	Backend  string            `yaml:"backend"`
+	Profiles map[string]string `yaml:"profiles"`
 }
 
-// PigoConfig holds pigo face detection configuration
+// PigoConfig holds pigo face detection configuration. CascadeFile is the cascade used
+// unless a request selects another, the embedded face cascade when empty; CascadeDir holds
+// further cascades requests and profiles can select by file name.
 type PigoConfig struct {
-	MinSize       int
-	MaxSize       int
-	ShiftFactor   float32
-	ScaleFactor   float32
-	IoUThreshold  float32
-	MinConfidence float32
+	MinSize       int     `yaml:"min_size" json:"min_size"`
+	MaxSize       int     `yaml:"max_size" json:"max_size"`
+	ShiftFactor   float32 `yaml:"shift_factor" json:"shift_factor"`
+	ScaleFactor   float32 `yaml:"scale_factor" json:"scale_factor"`
+	IoUThreshold  float32 `yaml:"iou_threshold" json:"iou_threshold"`
+	MinConfidence float32 `yaml:"min_confidence" json:"min_confidence"`
+	CascadeFile   string  `yaml:"cascade_file" json:"cascade_file"`
+	CascadeDir    string  `yaml:"cascade_dir" json:"cascade_dir"`
+}
+
+// SkinConfig holds configuration of the skin-color detection backend. MinConfidence is
+// between 0 and 1.
+type SkinConfig struct {
+	MinSize       int     `yaml:"min_size" json:"min_size"`
+	MinConfidence float32 `yaml:"min_confidence" json:"min_confidence"`
+}
+
+// ShadowConfig holds shadow detection configuration. A sampled fraction of live detections
+// is repeated with the shadow detector after the response is sent and the two results are
+// compared. Detector is a backend, optionally with a cascade as backend:cascade; Pigo
+// settings left zero take their value from the pigo section.
+type ShadowConfig struct {
+	Enabled    bool       `yaml:"enabled"`
+	Detector   string     `yaml:"detector"`
+	Pigo       PigoConfig `yaml:"pigo"`
+	SampleRate float64    `yaml:"sample_rate"`
+	MatchIoU   float64    `yaml:"match_iou"`
+	Workers    int        `yaml:"workers"`
+	QueueSize  int        `yaml:"queue_size"`
+	SampleLog  string     `yaml:"sample_log"`
 }
 
 // LimitsConfig holds various limits for the application
 type LimitsConfig struct {
-	MaxImageSize int64
-	MaxWidth     int
-	MaxHeight    int
+	MaxImageSize int64 `yaml:"max_image_size"`
+	MaxWidth     int   `yaml:"max_width"`
+	MaxHeight    int   `yaml:"max_height"`
+}
+
+// PoolConfig holds detection worker pool configuration
+type PoolConfig struct {
+	Workers      int           `yaml:"workers"`
+	QueueSize    int           `yaml:"queue_size"`
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+	RetryAfter   time.Duration `yaml:"retry_after"`
+}
+
+// BatchConfig holds batch processing configuration
+type BatchConfig struct {
+	MaxItems          int           `yaml:"max_items"`
+	Parallelism       int           `yaml:"parallelism"`
+	MaxRequestSize    int64         `yaml:"max_request_size"`
+	StreamMaxLineSize int           `yaml:"stream_max_line_size"`
+	StreamIdleTimeout time.Duration `yaml:"stream_idle_timeout"`
+	// Timeout bounds a whole buffered batch; images not started by then fail
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// JobsConfig holds asynchronous job processing configuration
+type JobsConfig struct {
+	Workers   int           `yaml:"workers"`
+	QueueSize int           `yaml:"queue_size"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Store     string        `yaml:"store"`
+	StoreDir  string        `yaml:"store_dir"`
+	Retention time.Duration `yaml:"retention"`
 }
 
-// Load loads configuration from environment variables with defaults
-func Load() *Config {
+// WebhookConfig holds callback delivery configuration
+type WebhookConfig struct {
+	Secret         string        `yaml:"secret"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Workers        int           `yaml:"workers"`
+	QueueSize      int           `yaml:"queue_size"`
+	DeadLetterPath string        `yaml:"dead_letter_path"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration. OTLP endpoint, headers and TLS
+// settings are read by the exporter from the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// AuthConfig holds API authentication configuration. Keys are JSON arrays of
+// {id, hash, scopes, expires_at, metadata} objects and JWT issuers are JSON arrays of
+// {issuer, audiences, jwks_url | jwks_file, subject_claim, scope_claim, scope_map} objects,
+// each inline or in a file.
+type AuthConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	APIKeys             string        `yaml:"api_keys"`
+	APIKeysFile         string        `yaml:"api_keys_file"`
+	JWTIssuers          string        `yaml:"jwt_issuers"`
+	JWTIssuersFile      string        `yaml:"jwt_issuers_file"`
+	JWTLeeway           time.Duration `yaml:"jwt_leeway"`
+	JWKSRefreshInterval time.Duration `yaml:"jwks_refresh_interval"`
+	PublicHealth        bool          `yaml:"public_health"`
+	PublicMetrics       bool          `yaml:"public_metrics"`
+}
+
+// RateLimitConfig holds per-client rate limiting configuration. Rates are requests per
+// second per endpoint class; a zero rate or quota disables that limit.
+type RateLimitConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	Backend      string  `yaml:"backend"`
+	TrustProxy   bool    `yaml:"trust_proxy"`
+	DetectRate   float64 `yaml:"detect_rate"`
+	DetectBurst  int     `yaml:"detect_burst"`
+	BatchRate    float64 `yaml:"batch_rate"`
+	BatchBurst   int     `yaml:"batch_burst"`
+	VisualRate   float64 `yaml:"visual_rate"`
+	VisualBurst  int     `yaml:"visual_burst"`
+	StatusRate   float64 `yaml:"status_rate"`
+	StatusBurst  int     `yaml:"status_burst"`
+	DailyQuota   int64   `yaml:"daily_quota"`
+	MonthlyQuota int64   `yaml:"monthly_quota"`
+}
+
+// HealthConfig holds readiness check configuration. Zero limits disable the matching check.
+type HealthConfig struct {
+	CheckInterval     time.Duration `yaml:"check_interval"`
+	CheckTimeout      time.Duration `yaml:"check_timeout"`
+	SelfTestBudget    time.Duration `yaml:"self_test_budget"`
+	MaxPoolSaturation float64       `yaml:"max_pool_saturation"`
+	MaxHeapBytes      int64         `yaml:"max_heap_bytes"`
+}
+
+// ReloadConfig holds hot reload configuration. File is set from CONFIG_FILE, never from
+// the file itself.
+type ReloadConfig struct {
+	File          string        `yaml:"-"`
+	WatchInterval time.Duration `yaml:"watch_interval"`
+}
+
+// AdminConfig holds admin API configuration. The admin API has its own listener so it
+// can be kept off the public network.
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", ":8080"),
-			ReadTimeout:  getDurationEnv("READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("IDLE_TIMEOUT", 120*time.Second),
+			Port:         ":8080",
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  120 * time.Second,
+			// Keep the pre-stop delay plus shutdown timeout below the orchestrator's grace period
+			PreStopDelay:    5 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Detection: DetectionConfig{
+			Backend:  "pigo",
+			Profiles: map[string]string{},
 		},
 		Pigo: PigoConfig{
-			MinSize:       getIntEnv("PIGO_MIN_SIZE", 25),
-			MaxSize:       getIntEnv("PIGO_MAX_SIZE", 1000),
-			ShiftFactor:   getFloat32Env("PIGO_SHIFT_FACTOR", 0.2),
-			ScaleFactor:   getFloat32Env("PIGO_SCALE_FACTOR", 1.1),
-			IoUThreshold:  getFloat32Env("PIGO_IOU_THRESHOLD", 0.6),
-			MinConfidence: getFloat32Env("PIGO_MIN_CONFIDENCE", 12.0),
+			MinSize:       25,
+			MaxSize:       1000,
+			ShiftFactor:   0.2,
+			ScaleFactor:   1.1,
+			IoUThreshold:  0.6,
+			MinConfidence: 12.0,
+		},
+		Skin: SkinConfig{
+			MinSize:       40,
+			MinConfidence: 0.6,
+		},
+		Shadow: ShadowConfig{
+			Detector:   "pigo",
+			SampleRate: 0.05,
+			MatchIoU:   0.5,
+			Workers:    1,
+			QueueSize:  100,
 		},
 		Limits: LimitsConfig{
-			MaxImageSize: getInt64Env("MAX_IMAGE_SIZE", 5242880), // 5MB
-			MaxWidth:     getIntEnv("MAX_WIDTH", 2000),
-			MaxHeight:    getIntEnv("MAX_HEIGHT", 2000),
+			MaxImageSize: 5242880, // 5MB
+			MaxWidth:     2000,
+			MaxHeight:    2000,
+		},
+		Pool: PoolConfig{
+			Workers:      0, // 0 uses GOMAXPROCS
+			QueueSize:    64,
+			QueueTimeout: 10 * time.Second,
+			RetryAfter:   time.Second,
+		},
+		Batch: BatchConfig{
+			MaxItems:          50,
+			Parallelism:       8,
+			MaxRequestSize:    52428800, // 50MB
+			StreamMaxLineSize: 8388608,  // 8MB
+			StreamIdleTimeout: 30 * time.Second,
+			Timeout:           2 * time.Minute,
+		},
+		Jobs: JobsConfig{
+			Workers:   4,
+			QueueSize: 1000,
+			Timeout:   2 * time.Minute,
+			Store:     "memory",
+			StoreDir:  "data/jobs",
+			Retention: 24 * time.Hour,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     time.Minute,
+			Timeout:        10 * time.Second,
+			Workers:        4,
+			QueueSize:      1000,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none", // otlp, stdout or none
+			ServiceName: "face-recognition-api",
+			SampleRatio: 1.0,
+		},
+		Auth: AuthConfig{
+			JWTLeeway:           30 * time.Second,
+			JWKSRefreshInterval: 15 * time.Minute,
+			PublicHealth:        true,
+			PublicMetrics:       true,
+		},
+		RateLimit: RateLimitConfig{
+			Backend:     "memory",
+			DetectRate:  10,
+			DetectBurst: 20,
+			BatchRate:   1,
+			BatchBurst:  2,
+			VisualRate:  2,
+			VisualBurst: 5,
+			StatusRate:  20,
+			StatusBurst: 40,
+		},
+		Health: HealthConfig{
+			CheckInterval:     15 * time.Second,
+			CheckTimeout:      5 * time.Second,
+			SelfTestBudget:    500 * time.Millisecond,
+			MaxPoolSaturation: 0.9,
+			MaxHeapBytes:      1073741824, // 1GB
+		},
+		Reload: ReloadConfig{
+			WatchInterval: 10 * time.Second,
+		},
+		Admin: AdminConfig{
+			Addr: ":9090",
 		},
 	}
 }
 
-// Helper functions for environment variable parsing
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+// Load builds the configuration from the defaults, the YAML file named by CONFIG_FILE if
+// any, and environment variables, which take precedence over the file. It fails on unknown
+// file keys, malformed values and settings that don't pass Validate.
+func Load() (*Config, error) {
+	cfg := Default()
 
-func getIntEnv(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+	if path := os.Getenv(FileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := cfg.decodeYAML(data); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
 		}
+		cfg.Reload.File = path
 	}
-	return defaultValue
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
 }
 
-func getInt64Env(key string, defaultValue int64) int64 {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
-			return intValue
-		}
+// ShadowPigo returns the pigo parameters of the shadow detector: the shadow.pigo settings
+// that are set, and the pigo section for the rest
+func (c *Config) ShadowPigo() PigoConfig {
+	p := c.Shadow.Pigo
+	if p.MinSize == 0 {
+		p.MinSize = c.Pigo.MinSize
+	}
+	if p.MaxSize == 0 {
+		p.MaxSize = c.Pigo.MaxSize
+	}
+	if p.ShiftFactor == 0 {
+		p.ShiftFactor = c.Pigo.ShiftFactor
+	}
+	if p.ScaleFactor == 0 {
+		p.ScaleFactor = c.Pigo.ScaleFactor
 	}
-	return defaultValue
+	if p.IoUThreshold == 0 {
+		p.IoUThreshold = c.Pigo.IoUThreshold
+	}
+	if p.MinConfidence == 0 {
+		p.MinConfidence = c.Pigo.MinConfidence
+	}
+	if p.CascadeFile == "" {
+		p.CascadeFile = c.Pigo.CascadeFile
+	}
+	if p.CascadeDir == "" {
+		p.CascadeDir = c.Pigo.CascadeDir
+	}
+	return p
 }
 
-func getFloat32Env(key string, defaultValue float32) float32 {
-	if value := os.Getenv(key); value != "" {
-		if floatValue, err := strconv.ParseFloat(value, 32); err == nil {
-			return float32(floatValue)
+// redacted replaces secret values that are set
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of c with secrets replaced, safe to show to operators
+func (c *Config) Redacted() *Config {
+	r := *c
+	for _, secret := range []*string{&r.Webhook.Secret, &r.Auth.APIKeys} {
+		if *secret != "" {
+			*secret = redacted
 		}
 	}
-	return defaultValue
+	return &r
 }
 
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
+// decodeYAML overlays the settings present in data onto cfg, rejecting unknown keys
+func (c *Config) decodeYAML(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
 	}
-	return defaultValue
+	return nil
 }
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader overrides settings with the environment variables that are set, collecting
+// every malformed value instead of stopping at the first one
+type envReader struct {
+	errs []error
+}
+
+// loadEnv applies environment variable overrides to c
+func (c *Config) loadEnv() error {
+	var env envReader
+
+	env.string("PORT", &c.Server.Port)
+	env.duration("READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.duration("SHUTDOWN_PRE_STOP_DELAY", &c.Server.PreStopDelay)
+	env.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+
+	env.string("DETECTION_BACKEND", &c.Detection.Backend)
+	env.stringMap("DETECTION_PROFILES", &c.Detection.Profiles)
+
+	env.int("PIGO_MIN_SIZE", &c.Pigo.MinSize)
+	env.int("PIGO_MAX_SIZE", &c.Pigo.MaxSize)
+	env.float32("PIGO_SHIFT_FACTOR", &c.Pigo.ShiftFactor)
+	env.float32("PIGO_SCALE_FACTOR", &c.Pigo.ScaleFactor)
+	env.float32("PIGO_IOU_THRESHOLD", &c.Pigo.IoUThreshold)
+	env.float32("PIGO_MIN_CONFIDENCE", &c.Pigo.MinConfidence)
+	env.string("PIGO_CASCADE_FILE", &c.Pigo.CascadeFile)
+	env.string("PIGO_CASCADE_DIR", &c.Pigo.CascadeDir)
+
+	env.int("SKIN_MIN_SIZE", &c.Skin.MinSize)
+	env.float32("SKIN_MIN_CONFIDENCE", &c.Skin.MinConfidence)
+
+	env.bool("SHADOW_ENABLED", &c.Shadow.Enabled)
+	env.string("SHADOW_DETECTOR", &c.Shadow.Detector)
+	env.int("SHADOW_PIGO_MIN_SIZE", &c.Shadow.Pigo.MinSize)
+	env.int("SHADOW_PIGO_MAX_SIZE", &c.Shadow.Pigo.MaxSize)
+	env.float32("SHADOW_PIGO_SHIFT_FACTOR", &c.Shadow.Pigo.ShiftFactor)
+	env.float32("SHADOW_PIGO_SCALE_FACTOR", &c.Shadow.Pigo.ScaleFactor)
+	env.float32("SHADOW_PIGO_IOU_THRESHOLD", &c.Shadow.Pigo.IoUThreshold)
+	env.float32("SHADOW_PIGO_MIN_CONFIDENCE", &c.Shadow.Pigo.MinConfidence)
+	env.string("SHADOW_PIGO_CASCADE_FILE", &c.Shadow.Pigo.CascadeFile)
+	env.string("SHADOW_PIGO_CASCADE_DIR", &c.Shadow.Pigo.CascadeDir)
+	env.float64("SHADOW_SAMPLE_RATE", &c.Shadow.SampleRate)
+	env.float64("SHADOW_MATCH_IOU", &c.Shadow.MatchIoU)
+	env.int("SHADOW_WORKERS", &c.Shadow.Workers)
+	env.int("SHADOW_QUEUE_SIZE", &c.Shadow.QueueSize)
+	env.string("SHADOW_SAMPLE_LOG", &c.Shadow.SampleLog)
+
+	env.int64("MAX_IMAGE_SIZE", &c.Limits.MaxImageSize)
+	env.int("MAX_WIDTH", &c.Limits.MaxWidth)
+	env.int("MAX_HEIGHT", &c.Limits.MaxHeight)
+
+	env.int("DETECTION_WORKERS", &c.Pool.Workers)
+	env.int("DETECTION_QUEUE_SIZE", &c.Pool.QueueSize)
+	env.duration("DETECTION_QUEUE_TIMEOUT", &c.Pool.QueueTimeout)
+	env.duration("DETECTION_RETRY_AFTER", &c.Pool.RetryAfter)
+
+	env.int("BATCH_MAX_ITEMS", &c.Batch.MaxItems)
+	env.int("BATCH_PARALLELISM", &c.Batch.Parallelism)
+	env.int64("BATCH_MAX_REQUEST_SIZE", &c.Batch.MaxRequestSize)
+	env.int("BATCH_STREAM_MAX_LINE_SIZE", &c.Batch.StreamMaxLineSize)
+	env.duration("BATCH_STREAM_IDLE_TIMEOUT", &c.Batch.StreamIdleTimeout)
+	env.duration("BATCH_TIMEOUT", &c.Batch.Timeout)
+
+	env.int("JOBS_WORKERS", &c.Jobs.Workers)
+	env.int("JOBS_QUEUE_SIZE", &c.Jobs.QueueSize)
+	env.duration("JOBS_TIMEOUT", &c.Jobs.Timeout)
+	env.string("JOBS_STORE", &c.Jobs.Store)
+	env.string("JOBS_STORE_DIR", &c.Jobs.StoreDir)
+	env.duration("JOBS_RETENTION", &c.Jobs.Retention)
+
+	env.string("WEBHOOK_SECRET", &c.Webhook.Secret)
+	env.int("WEBHOOK_MAX_ATTEMPTS", &c.Webhook.MaxAttempts)
+	env.duration("WEBHOOK_INITIAL_BACKOFF", &c.Webhook.InitialBackoff)
+	env.duration("WEBHOOK_MAX_BACKOFF", &c.Webhook.MaxBackoff)
+	env.duration("WEBHOOK_TIMEOUT", &c.Webhook.Timeout)
+	env.int("WEBHOOK_WORKERS", &c.Webhook.Workers)
+	env.int("WEBHOOK_QUEUE_SIZE", &c.Webhook.QueueSize)
+	env.string("WEBHOOK_DEAD_LETTER_PATH", &c.Webhook.DeadLetterPath)
+
+	env.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	env.string("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	env.float64("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	env.bool("AUTH_ENABLED", &c.Auth.Enabled)
+	env.string("AUTH_API_KEYS", &c.Auth.APIKeys)
+	env.string("AUTH_API_KEYS_FILE", &c.Auth.APIKeysFile)
+	env.string("AUTH_JWT_ISSUERS", &c.Auth.JWTIssuers)
+	env.string("AUTH_JWT_ISSUERS_FILE", &c.Auth.JWTIssuersFile)
+	env.duration("AUTH_JWT_LEEWAY", &c.Auth.JWTLeeway)
+	env.duration("AUTH_JWKS_REFRESH_INTERVAL", &c.Auth.JWKSRefreshInterval)
+	env.bool("AUTH_PUBLIC_HEALTH", &c.Auth.PublicHealth)
+	env.bool("AUTH_PUBLIC_METRICS", &c.Auth.PublicMetrics)
+
+	env.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	env.string("RATE_LIMIT_BACKEND", &c.RateLimit.Backend)
+	env.bool("RATE_LIMIT_TRUST_PROXY", &c.RateLimit.TrustProxy)
+	env.float64("RATE_LIMIT_DETECT_RATE", &c.RateLimit.DetectRate)
+	env.int("RATE_LIMIT_DETECT_BURST", &c.RateLimit.DetectBurst)
+	env.float64("RATE_LIMIT_BATCH_RATE", &c.RateLimit.BatchRate)
+	env.int("RATE_LIMIT_BATCH_BURST", &c.RateLimit.BatchBurst)
+	env.float64("RATE_LIMIT_VISUAL_RATE", &c.RateLimit.VisualRate)
+	env.int("RATE_LIMIT_VISUAL_BURST", &c.RateLimit.VisualBurst)
+	env.float64("RATE_LIMIT_STATUS_RATE", &c.RateLimit.StatusRate)
+	env.int("RATE_LIMIT_STATUS_BURST", &c.RateLimit.StatusBurst)
+	env.int64("RATE_LIMIT_DAILY_QUOTA", &c.RateLimit.DailyQuota)
+	env.int64("RATE_LIMIT_MONTHLY_QUOTA", &c.RateLimit.MonthlyQuota)
+
+	env.duration("HEALTH_CHECK_INTERVAL", &c.Health.CheckInterval)
+	env.duration("HEALTH_CHECK_TIMEOUT", &c.Health.CheckTimeout)
+	env.duration("HEALTH_SELF_TEST_BUDGET", &c.Health.SelfTestBudget)
+	env.float64("HEALTH_MAX_POOL_SATURATION", &c.Health.MaxPoolSaturation)
+	env.int64("HEALTH_MAX_HEAP_BYTES", &c.Health.MaxHeapBytes)
+
+	env.duration("CONFIG_WATCH_INTERVAL", &c.Reload.WatchInterval)
+
+	env.bool("ADMIN_ENABLED", &c.Admin.Enabled)
+	env.string("ADMIN_ADDR", &c.Admin.Addr)
+
+	return errors.Join(env.errs...)
+}
+
+// lookup returns the value of key if it is set and not empty
+func (e *envReader) lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
+}
+
+// fail records a malformed value of key
+func (e *envReader) fail(key, value, want string) {
+	e.errs = append(e.errs, fmt.Errorf("%s=%q is not %s", key, value, want))
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail(key, value, "an integer")
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (e *envReader) int64(key string, dst *int64) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			e.fail(key, value, "an integer")
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (e *envReader) float32(key string, dst *float32) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			e.fail(key, value, "a number")
+			return
+		}
+		*dst = float32(parsed)
+	}
+}
+
+func (e *envReader) float64(key string, dst *float64) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.fail(key, value, "a number")
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			e.fail(key, value, "a boolean")
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			e.fail(key, value, "a duration such as 30s or 5m")
+			return
+		}
+		*dst = parsed
+	}
+}
+
+// stringMap reads comma-separated name=value pairs, replacing the whole map
+func (e *envReader) stringMap(key string, dst *map[string]string) {
+	if value, ok := e.lookup(key); ok {
+		parsed := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			name, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || name == "" {
+				e.fail(key, value, "a list of name=value pairs")
+				return
+			}
+			parsed[name] = val
+		}
+		*dst = parsed
+	}
+}
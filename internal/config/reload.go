@@ -0,0 +1,216 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/metrics"
+)
+
+// ApplyFunc switches the running service over to cfg. It must either apply every
+// reloadable setting or, when it returns an error, none of them.
+type ApplyFunc func(cfg *Config) error
+
+// Reloader reloads the configuration on SIGHUP and whenever the config file or the auth
+// files it references change. Only detector parameters, limits, auth credentials and rate
+// limits take effect; other changes are logged as requiring a restart.
+type Reloader struct {
+	apply  ApplyFunc
+	logger *logrus.Logger
+
+	mu          sync.Mutex
+	current     *Config
+	fingerprint [sha256.Size]byte
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReloader creates a new reloader for the running configuration cfg
+func NewReloader(cfg *Config, apply ApplyFunc, logger *logrus.Logger) *Reloader {
+	return &Reloader{
+		apply:       apply,
+		logger:      logger,
+		current:     cfg,
+		fingerprint: fingerprint(cfg),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start listens for SIGHUP and, when a config file is used, polls the watched files
+// every WatchInterval
+func (r *Reloader) Start() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer close(r.done)
+		defer signal.Stop(hangup)
+
+		var poll <-chan time.Time
+		if interval := r.current.Reload.WatchInterval; interval > 0 && r.current.Reload.File != "" {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+
+		for {
+			select {
+			case <-hangup:
+				r.logger.Info("Received SIGHUP, reloading configuration")
+				r.Reload()
+			case <-poll:
+				if r.changed() {
+					r.logger.Info("Configuration files changed, reloading configuration")
+					r.Reload()
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops listening for reload triggers
+func (r *Reloader) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// Current returns the configuration in effect. Settings that require a restart keep their
+// startup values.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload loads and validates the configuration and applies it. On failure the running
+// configuration is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load()
+	// Remember the files even when they are invalid, so a broken edit is reported once
+	r.fingerprint = fingerprint(r.current)
+	if err == nil {
+		r.fingerprint = fingerprint(next)
+		err = r.apply(next)
+	}
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("error").Inc()
+		r.logger.WithError(err).Error("Configuration reload failed, keeping the running configuration")
+		return err
+	}
+
+	if pending := RestartRequired(r.current, next); len(pending) > 0 {
+		r.logger.WithField("settings", pending).Warn("Changed settings take effect after a restart")
+	}
+	r.current = withRestartSettings(r.current, next)
+
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	r.logger.Info("Configuration reloaded")
+	return nil
+}
+
+// changed reports whether the watched files differ from the last reload
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fingerprint(r.current) != r.fingerprint
+}
+
+// fingerprint hashes the config file and the auth files it references. Unreadable files
+// hash their error, so they count as changed once they come back.
+func fingerprint(cfg *Config) [sha256.Size]byte {
+	h := sha256.New()
+	for _, path := range []string{cfg.Reload.File, cfg.Auth.APIKeysFile, cfg.Auth.JWTIssuersFile} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			data = []byte(err.Error())
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(data))
+		h.Write(data)
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// reloadable returns a copy of cfg without the settings that can change at runtime
+func reloadable(cfg *Config) Config {
+	c := *cfg
+	c.Pigo = PigoConfig{}
+	c.Skin = SkinConfig{}
+	c.Shadow.Pigo = PigoConfig{}
+	c.Limits = LimitsConfig{}
+
+	c.Auth.APIKeys, c.Auth.APIKeysFile = "", ""
+	c.Auth.JWTIssuers, c.Auth.JWTIssuersFile = "", ""
+	c.Auth.JWTLeeway, c.Auth.JWKSRefreshInterval = 0, 0
+
+	c.RateLimit = RateLimitConfig{
+		Enabled:    cfg.RateLimit.Enabled,
+		Backend:    cfg.RateLimit.Backend,
+		TrustProxy: cfg.RateLimit.TrustProxy,
+	}
+	return c
+}
+
+// RestartRequired lists the settings, by config file path, that differ between old and new
+// but can't change without a restart
+func RestartRequired(old, new *Config) []string {
+	a, b := reloadable(old), reloadable(new)
+	var changed []string
+
+	sections := reflect.TypeOf(a)
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		oldSection, newSection := reflect.ValueOf(a).Field(i), reflect.ValueOf(b).Field(i)
+
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			if field.Tag.Get("yaml") == "-" {
+				continue
+			}
+			if !reflect.DeepEqual(oldSection.Field(j).Interface(), newSection.Field(j).Interface()) {
+				changed = append(changed, section.Tag.Get("yaml")+"."+field.Tag.Get("yaml"))
+			}
+		}
+	}
+	return changed
+}
+
+// withRestartSettings returns next with the settings that require a restart taken from
+// running, so the result describes what is actually in effect
+func withRestartSettings(running, next *Config) *Config {
+	c := reloadable(running)
+	c.Pigo = next.Pigo
+	c.Skin = next.Skin
+	c.Shadow.Pigo = next.Shadow.Pigo
+	c.Limits = next.Limits
+
+	c.Auth.APIKeys, c.Auth.APIKeysFile = next.Auth.APIKeys, next.Auth.APIKeysFile
+	c.Auth.JWTIssuers, c.Auth.JWTIssuersFile = next.Auth.JWTIssuers, next.Auth.JWTIssuersFile
+	c.Auth.JWTLeeway, c.Auth.JWKSRefreshInterval = next.Auth.JWTLeeway, next.Auth.JWKSRefreshInterval
+
+	c.RateLimit = next.RateLimit
+	c.RateLimit.Enabled = running.RateLimit.Enabled
+	c.RateLimit.Backend = running.RateLimit.Backend
+	c.RateLimit.TrustProxy = running.RateLimit.TrustProxy
+	return &c
+}
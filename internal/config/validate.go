@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// validator collects every invalid setting so startup reports them all at once. Settings
+// are named by their path in the config file.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) positive(name string, value int64) {
+	if value <= 0 {
+		v.fail("%s must be positive, got %d", name, value)
+	}
+}
+
+func (v *validator) nonNegative(name string, value int64) {
+	if value < 0 {
+		v.fail("%s must not be negative, got %d", name, value)
+	}
+}
+
+func (v *validator) positiveDuration(name string, value time.Duration) {
+	if value <= 0 {
+		v.fail("%s must be a positive duration, got %s", name, value)
+	}
+}
+
+func (v *validator) nonNegativeDuration(name string, value time.Duration) {
+	if value < 0 {
+		v.fail("%s must not be negative, got %s", name, value)
+	}
+}
+
+func (v *validator) fraction(name string, value float64) {
+	if value < 0 || value > 1 {
+		v.fail("%s must be between 0 and 1, got %g", name, value)
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.fail("%s must be one of %q, got %q", name, allowed, value)
+}
+
+// Validate reports every setting that is out of range or inconsistent with another
+func (c *Config) Validate() error {
+	var v validator
+
+	if c.Server.Port == "" {
+		v.fail("server.port must not be empty")
+	}
+	v.positiveDuration("server.read_timeout", c.Server.ReadTimeout)
+	v.positiveDuration("server.write_timeout", c.Server.WriteTimeout)
+	v.positiveDuration("server.idle_timeout", c.Server.IdleTimeout)
+	v.nonNegativeDuration("server.pre_stop_delay", c.Server.PreStopDelay)
+	v.positiveDuration("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	v.oneOf("detection.backend", c.Detection.Backend, Backends...)
+	for profile, selection := range c.Detection.Profiles {
+		backend, _, _ := strings.Cut(selection, ":")
+		v.oneOf("detection.profiles."+profile, backend, Backends...)
+	}
+
+	v.errs = append(v.errs, c.Pigo.Validate())
+	v.errs = append(v.errs, c.Skin.Validate())
+
+	if c.Shadow.Enabled {
+		backend, _, _ := strings.Cut(c.Shadow.Detector, ":")
+		v.oneOf("shadow.detector", backend, Backends...)
+		v.errs = append(v.errs, c.ShadowPigo().validate("shadow.pigo"))
+		v.fraction("shadow.sample_rate", c.Shadow.SampleRate)
+		if c.Shadow.MatchIoU <= 0 || c.Shadow.MatchIoU > 1 {
+			v.fail("shadow.match_iou must be in (0, 1], got %g", c.Shadow.MatchIoU)
+		}
+		v.positive("shadow.workers", int64(c.Shadow.Workers))
+		v.positive("shadow.queue_size", int64(c.Shadow.QueueSize))
+	}
+
+	v.positive("limits.max_image_size", c.Limits.MaxImageSize)
+	v.positive("limits.max_width", int64(c.Limits.MaxWidth))
+	v.positive("limits.max_height", int64(c.Limits.MaxHeight))
+
+	v.nonNegative("pool.workers", int64(c.Pool.Workers))
+	v.nonNegative("pool.queue_size", int64(c.Pool.QueueSize))
+	v.positiveDuration("pool.queue_timeout", c.Pool.QueueTimeout)
+	v.positiveDuration("pool.retry_after", c.Pool.RetryAfter)
+
+	v.positive("batch.max_items", int64(c.Batch.MaxItems))
+	v.positive("batch.parallelism", int64(c.Batch.Parallelism))
+	v.positive("batch.max_request_size", c.Batch.MaxRequestSize)
+	v.positive("batch.stream_max_line_size", int64(c.Batch.StreamMaxLineSize))
+	v.positiveDuration("batch.stream_idle_timeout", c.Batch.StreamIdleTimeout)
+	v.positiveDuration("batch.timeout", c.Batch.Timeout)
+	if c.Batch.MaxRequestSize > 0 && c.Limits.MaxImageSize > c.Batch.MaxRequestSize {
+		v.fail("batch.max_request_size (%d) must be at least limits.max_image_size (%d)",
+			c.Batch.MaxRequestSize, c.Limits.MaxImageSize)
+	}
+
+	v.positive("jobs.workers", int64(c.Jobs.Workers))
+	v.positive("jobs.queue_size", int64(c.Jobs.QueueSize))
+	v.positiveDuration("jobs.timeout", c.Jobs.Timeout)
+	v.positiveDuration("jobs.retention", c.Jobs.Retention)
+	v.oneOf("jobs.store", c.Jobs.Store, "memory", "file")
+	if c.Jobs.Store == "file" && c.Jobs.StoreDir == "" {
+		v.fail("jobs.store_dir must be set when jobs.store is file")
+	}
+
+	v.positive("webhook.max_attempts", int64(c.Webhook.MaxAttempts))
+	v.positiveDuration("webhook.initial_backoff", c.Webhook.InitialBackoff)
+	v.positiveDuration("webhook.max_backoff", c.Webhook.MaxBackoff)
+	v.positiveDuration("webhook.timeout", c.Webhook.Timeout)
+	v.positive("webhook.workers", int64(c.Webhook.Workers))
+	v.positive("webhook.queue_size", int64(c.Webhook.QueueSize))
+	if c.Webhook.MaxBackoff < c.Webhook.InitialBackoff {
+		v.fail("webhook.max_backoff (%s) must be at least webhook.initial_backoff (%s)",
+			c.Webhook.MaxBackoff, c.Webhook.InitialBackoff)
+	}
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout")
+	v.fraction("tracing.sample_ratio", c.Tracing.SampleRatio)
+
+	v.nonNegativeDuration("auth.jwt_leeway", c.Auth.JWTLeeway)
+	v.positiveDuration("auth.jwks_refresh_interval", c.Auth.JWKSRefreshInterval)
+
+	v.errs = append(v.errs, c.RateLimit.Validate())
+
+	v.positiveDuration("health.check_interval", c.Health.CheckInterval)
+	v.positiveDuration("health.check_timeout", c.Health.CheckTimeout)
+	v.positiveDuration("health.self_test_budget", c.Health.SelfTestBudget)
+	v.fraction("health.max_pool_saturation", c.Health.MaxPoolSaturation)
+	v.nonNegative("health.max_heap_bytes", c.Health.MaxHeapBytes)
+
+	v.nonNegativeDuration("reload.watch_interval", c.Reload.WatchInterval)
+
+	if c.Admin.Enabled {
+		if c.Admin.Addr == "" {
+			v.fail("admin.addr must be set when admin.enabled is true")
+		}
+		if c.Admin.Addr == c.Server.Port {
+			v.fail("admin.addr must differ from server.port, got %q for both", c.Admin.Addr)
+		}
+		if !c.Auth.Enabled {
+			v.fail("admin.enabled requires auth.enabled, the admin API is never served without authentication")
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// Validate reports pigo parameters the detector can't run with
+func (p PigoConfig) Validate() error {
+	return p.validate("pigo")
+}
+
+// validate reports pigo parameters the detector can't run with, naming them under section
+func (p PigoConfig) validate(section string) error {
+	var v validator
+
+	v.positive(section+".min_size", int64(p.MinSize))
+	if p.MaxSize < p.MinSize {
+		v.fail("%[1]s.max_size (%[2]d) must be at least %[1]s.min_size (%[3]d)", section, p.MaxSize, p.MinSize)
+	}
+	if p.ShiftFactor <= 0 || p.ShiftFactor > 1 {
+		v.fail("%s.shift_factor must be in (0, 1], got %g", section, p.ShiftFactor)
+	}
+	if p.ScaleFactor <= 1 {
+		v.fail("%s.scale_factor must be greater than 1, got %g", section, p.ScaleFactor)
+	}
+	if p.IoUThreshold <= 0 || p.IoUThreshold > 1 {
+		v.fail("%s.iou_threshold must be in (0, 1], got %g", section, p.IoUThreshold)
+	}
+	if p.MinConfidence < 0 {
+		v.fail("%s.min_confidence must not be negative, got %g", section, p.MinConfidence)
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// Validate reports skin detector parameters out of range
+func (s SkinConfig) Validate() error {
+	var v validator
+
+	v.positive("skin.min_size", int64(s.MinSize))
+	v.fraction("skin.min_confidence", float64(s.MinConfidence))
+
+	return errors.Join(v.errs...)
+}
+
+// Validate reports rate limits that can't be enforced
+func (r RateLimitConfig) Validate() error {
+	var v validator
+
+	v.oneOf("rate_limit.backend", r.Backend, "memory")
+	classes := []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{"detect", r.DetectRate, r.DetectBurst},
+		{"batch", r.BatchRate, r.BatchBurst},
+		{"visual", r.VisualRate, r.VisualBurst},
+		{"status", r.StatusRate, r.StatusBurst},
+	}
+	for _, class := range classes {
+		if class.rate < 0 {
+			v.fail("rate_limit.%s_rate must not be negative, got %g", class.name, class.rate)
+		}
+		if class.rate > 0 && class.burst < 1 {
+			v.fail("rate_limit.%s_burst must be at least 1 when rate_limit.%s_rate is set, got %d",
+				class.name, class.name, class.burst)
+		}
+	}
+	v.nonNegative("rate_limit.daily_quota", r.DailyQuota)
+	v.nonNegative("rate_limit.monthly_quota", r.MonthlyQuota)
+
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,237 @@
+package evaluation
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"face-recognition-api/internal/models"
+)
+
+// Annotation is the ground truth of one image: the faces it contains, and regions whose
+// faces count neither as found nor as missed, such as WIDER FACE boxes marked invalid.
+// Image is relative to the dataset's image directory.
+type Annotation struct {
+	Image  string        `json:"image"`
+	Faces  []models.Face `json:"faces"`
+	Ignore []models.Face `json:"ignore,omitempty"`
+}
+
+// Formats lists the annotation formats Load reads
+var Formats = []string{"fddb", "wider", "json"}
+
+// Load reads the annotation files matching pattern, in name order, in the given format
+func Load(format, pattern string) ([]Annotation, error) {
+	var read func(io.Reader) ([]Annotation, error)
+	switch format {
+	case "fddb":
+		read = ReadFDDB
+	case "wider":
+		read = ReadWIDER
+	case "json":
+		read = ReadJSON
+	default:
+		return nil, fmt.Errorf("unknown annotation format %q, want one of %q", format, Formats)
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation pattern: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no annotation files match %s", pattern)
+	}
+
+	var annotations []Annotation
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := read(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		annotations = append(annotations, loaded...)
+	}
+	return annotations, nil
+}
+
+// lineReader reads the non-blank lines of a text annotation file, tracking line numbers
+// for error messages
+type lineReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{scanner: bufio.NewScanner(r)}
+}
+
+// next returns the next non-blank line, trimmed, or io.EOF
+func (l *lineReader) next() (string, error) {
+	for l.scanner.Scan() {
+		l.line++
+		if text := strings.TrimSpace(l.scanner.Text()); text != "" {
+			return text, nil
+		}
+	}
+	if err := l.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// count reads a line holding a face count
+func (l *lineReader) count() (int, error) {
+	text, err := l.next()
+	if err != nil {
+		return 0, l.unexpected(err)
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("line %d: %q is not a face count", l.line, text)
+	}
+	return n, nil
+}
+
+// numbers reads a line of at least want space-separated numbers
+func (l *lineReader) numbers(want int) ([]float64, error) {
+	text, err := l.next()
+	if err != nil {
+		return nil, l.unexpected(err)
+	}
+	fields := strings.Fields(text)
+	if len(fields) < want {
+		return nil, fmt.Errorf("line %d: want %d values, got %d", l.line, want, len(fields))
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return nil, fmt.Errorf("line %d: %q is not a number", l.line, field)
+		}
+	}
+	return values, nil
+}
+
+// unexpected reports a file that ends in the middle of an image's faces
+func (l *lineReader) unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("line %d: unexpected end of file", l.line)
+	}
+	return err
+}
+
+// ReadFDDB reads FDDB ellipse lists: an image path without extension, a face count and a
+// "major_radius minor_radius angle center_x center_y 1" line per face. Faces are the
+// bounding boxes of the ellipses, and images are taken to be .jpg files.
+func ReadFDDB(r io.Reader) ([]Annotation, error) {
+	lines := newLineReader(r)
+	var annotations []Annotation
+
+	for {
+		path, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			return annotations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := lines.count()
+		if err != nil {
+			return nil, err
+		}
+		annotation := Annotation{Image: path + ".jpg", Faces: make([]models.Face, 0, n)}
+		for i := 0; i < n; i++ {
+			v, err := lines.numbers(5)
+			if err != nil {
+				return nil, err
+			}
+			annotation.Faces = append(annotation.Faces, ellipseBox(v[0], v[1], v[2], v[3], v[4]))
+		}
+		annotations = append(annotations, annotation)
+	}
+}
+
+// ellipseBox returns the bounding box of an ellipse with the given radii, rotation of the
+// major axis in radians and center
+func ellipseBox(major, minor, angle, cx, cy float64) models.Face {
+	sin, cos := math.Sincos(angle)
+	halfWidth := math.Hypot(major*cos, minor*sin)
+	halfHeight := math.Hypot(major*sin, minor*cos)
+
+	return models.Face{
+		X:      int(math.Round(cx - halfWidth)),
+		Y:      int(math.Round(cy - halfHeight)),
+		Width:  int(math.Round(2 * halfWidth)),
+		Height: int(math.Round(2 * halfHeight)),
+	}
+}
+
+// ReadWIDER reads WIDER FACE ground truth: an image path, a face count and an
+// "x y width height blur expression illumination invalid occlusion pose" line per face.
+// Images without faces still have one line of zeros. Invalid faces become ignored regions
+// and boxes without area are skipped.
+func ReadWIDER(r io.Reader) ([]Annotation, error) {
+	lines := newLineReader(r)
+	var annotations []Annotation
+
+	for {
+		path, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			return annotations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := lines.count()
+		if err != nil {
+			return nil, err
+		}
+		annotation := Annotation{Image: path, Faces: make([]models.Face, 0, n)}
+		for i := 0; i < max(n, 1); i++ {
+			v, err := lines.numbers(4)
+			if err != nil {
+				return nil, err
+			}
+			face := models.Face{X: int(v[0]), Y: int(v[1]), Width: int(v[2]), Height: int(v[3])}
+			if n == 0 || face.Width <= 0 || face.Height <= 0 {
+				continue
+			}
+			if len(v) >= 8 && v[7] != 0 {
+				annotation.Ignore = append(annotation.Ignore, face)
+				continue
+			}
+			annotation.Faces = append(annotation.Faces, face)
+		}
+		annotations = append(annotations, annotation)
+	}
+}
+
+// ReadJSON reads a JSON array of annotations:
+// [{"image": "a.jpg", "faces": [{"x": 1, "y": 2, "width": 30, "height": 40}], "ignore": []}]
+func ReadJSON(r io.Reader) ([]Annotation, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var annotations []Annotation
+	if err := decoder.Decode(&annotations); err != nil {
+		return nil, fmt.Errorf("invalid JSON annotations: %w", err)
+	}
+	for i, annotation := range annotations {
+		if annotation.Image == "" {
+			return nil, fmt.Errorf("annotation %d has no image", i)
+		}
+	}
+	return annotations, nil
+}
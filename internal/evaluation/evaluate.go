@@ -0,0 +1,360 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/services"
+)
+
+// Options controls how detections are scored against the ground truth
+type Options struct {
+	// IoUThresholds are the overlaps at which a detection counts as finding a face
+	IoUThresholds []float64
+	// SizeEdges split faces into size buckets by the square root of their area, in pixels;
+	// without edges there is no size breakdown
+	SizeEdges []float64
+	// MinConfidence is the operating point precision, recall and F1 are reported at
+	MinConfidence float32
+	// Sweep lists the min confidences to report metrics at; when empty, SweepSteps
+	// intervals divide the scores from zero to the highest
+	Sweep      []float32
+	SweepSteps int
+}
+
+// CheckIoU returns an error unless iou is a usable matching threshold, in (0, 1]. At zero
+// a detection that doesn't overlap a face at all would count as finding it.
+func CheckIoU(iou float64) error {
+	if !(iou > 0 && iou <= 1) {
+		return fmt.Errorf("IoU threshold %g must be greater than 0 and at most 1", iou)
+	}
+	return nil
+}
+
+// Report is the outcome of an evaluation
+type Report struct {
+	Detector      models.DetectorInfo `json:"detector"`
+	MinConfidence float32             `json:"min_confidence"`
+	Images        int                 `json:"images"`
+	ImagesFailed  int                 `json:"images_failed"`
+	Faces         int                 `json:"faces"`
+	IgnoredFaces  int                 `json:"ignored_faces"`
+	Latency       Latency             `json:"latency"`
+	IoUThresholds []ThresholdReport   `json:"iou_thresholds"`
+}
+
+// Latency summarizes detection time per image
+type Latency struct {
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Metrics are detection counts and the scores derived from them. AP is the same at every
+// min confidence, the area under the whole precision-recall curve.
+type Metrics struct {
+	AP             float64 `json:"ap"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+}
+
+// ThresholdReport holds the metrics at one IoU threshold
+type ThresholdReport struct {
+	IoU float64 `json:"iou"`
+	Metrics
+	// BestF1 is the sweep point with the highest F1
+	BestF1 SweepPoint   `json:"best_f1"`
+	Sizes  []SizeReport `json:"sizes,omitempty"`
+	Sweep  []SweepPoint `json:"sweep"`
+}
+
+// SizeReport holds the metrics of faces whose size, the square root of their area, is at
+// least MinSize and below MaxSize; a zero MaxSize is unbounded
+type SizeReport struct {
+	Name    string  `json:"name"`
+	MinSize float64 `json:"min_size"`
+	MaxSize float64 `json:"max_size,omitempty"`
+	Faces   int     `json:"faces"`
+	Metrics
+}
+
+// SweepPoint holds the metrics at one min confidence
+type SweepPoint struct {
+	MinConfidence float32 `json:"min_confidence"`
+	Metrics
+}
+
+// Result is what a detector found in one image. Faces should include every scored
+// detection, so the detector must run with its min confidence at zero.
+type Result struct {
+	Image    string
+	Faces    []models.Face
+	Duration time.Duration
+	Err      error
+}
+
+// Evaluate scores results[i] against annotations[i]. Images that failed to load or detect
+// are left out.
+func Evaluate(annotations []Annotation, results []Result, opts Options) *Report {
+	report := &Report{MinConfidence: opts.MinConfidence, Images: len(annotations)}
+
+	var kept []Annotation
+	var keptResults []Result
+	var durations []time.Duration
+	var maxScore float32
+	for i, annotation := range annotations {
+		if results[i].Err != nil {
+			report.ImagesFailed++
+			continue
+		}
+		kept = append(kept, annotation)
+		keptResults = append(keptResults, results[i])
+		durations = append(durations, results[i].Duration)
+		report.Faces += len(annotation.Faces)
+		report.IgnoredFaces += len(annotation.Ignore)
+		for _, face := range results[i].Faces {
+			maxScore = max(maxScore, face.Confidence)
+		}
+	}
+	report.Latency = summarize(durations)
+
+	points := opts.Sweep
+	if len(points) == 0 {
+		points = sweep(maxScore, opts.SweepSteps)
+	}
+
+	for _, iou := range opts.IoUThresholds {
+		all := newCurve(kept, keptResults, iou, nil)
+		threshold := ThresholdReport{IoU: iou, Metrics: all.at(opts.MinConfidence)}
+
+		for _, point := range points {
+			p := SweepPoint{MinConfidence: point, Metrics: all.at(point)}
+			threshold.Sweep = append(threshold.Sweep, p)
+			if p.F1 > threshold.BestF1.F1 {
+				threshold.BestF1 = p
+			}
+		}
+
+		if len(opts.SizeEdges) > 0 {
+			for _, size := range SizeBuckets(opts.SizeEdges) {
+				c := newCurve(kept, keptResults, iou, size.contains)
+				size.Faces = c.positives
+				size.Metrics = c.at(opts.MinConfidence)
+				threshold.Sizes = append(threshold.Sizes, size)
+			}
+		}
+		report.IoUThresholds = append(report.IoUThresholds, threshold)
+	}
+	return report
+}
+
+// SizeBuckets returns the size ranges the edges split faces into
+func SizeBuckets(edges []float64) []SizeReport {
+	edges = append([]float64(nil), edges...)
+	sort.Float64s(edges)
+
+	buckets := make([]SizeReport, 0, len(edges)+1)
+	lower := 0.0
+	for _, edge := range edges {
+		buckets = append(buckets, SizeReport{Name: fmt.Sprintf("%g-%g", lower, edge), MinSize: lower, MaxSize: edge})
+		lower = edge
+	}
+	return append(buckets, SizeReport{Name: fmt.Sprintf("%g+", lower), MinSize: lower})
+}
+
+// contains reports whether face falls in the size bucket
+func (s SizeReport) contains(face models.Face) bool {
+	size := faceSize(face)
+	return size >= s.MinSize && (s.MaxSize == 0 || size < s.MaxSize)
+}
+
+// faceSize returns the side of the square with the face's area
+func faceSize(face models.Face) float64 {
+	return math.Sqrt(float64(face.Width) * float64(face.Height))
+}
+
+// sweep returns steps+1 evenly spaced min confidences from zero to the highest score
+func sweep(maxScore float32, steps int) []float32 {
+	steps = max(steps, 1)
+	points := make([]float32, steps+1)
+	for i := range points {
+		points[i] = maxScore * float32(i) / float32(steps)
+	}
+	return points
+}
+
+// scoredDetection is a detection that either found a face or is a false positive
+type scoredDetection struct {
+	confidence float32
+	found      bool
+}
+
+// curve is every scored detection of a dataset, best first, with the number of faces to find
+type curve struct {
+	detections []scoredDetection
+	positives  int
+	ap         float64
+}
+
+// newCurve matches the detections of every image to its faces. When include is set, faces
+// it rejects are treated as ignored regions, and detections it rejects that find no face
+// are not counted.
+func newCurve(annotations []Annotation, results []Result, iou float64, include func(models.Face) bool) *curve {
+	c := &curve{}
+	for i, annotation := range annotations {
+		faces, ignore := annotation.Faces, annotation.Ignore
+		if include != nil {
+			faces, ignore = nil, append([]models.Face(nil), annotation.Ignore...)
+			for _, face := range annotation.Faces {
+				if include(face) {
+					faces = append(faces, face)
+				} else {
+					ignore = append(ignore, face)
+				}
+			}
+		}
+
+		c.positives += len(faces)
+		c.detections = append(c.detections, matchImage(faces, ignore, results[i].Faces, iou, include)...)
+	}
+
+	sort.SliceStable(c.detections, func(i, j int) bool {
+		return c.detections[i].confidence > c.detections[j].confidence
+	})
+	c.ap = c.averagePrecision()
+	return c
+}
+
+// matchImage assigns each detection, most confident first, to the unclaimed face it
+// overlaps most. Detections that find no face but cover an ignored region, or that
+// include rejects, are dropped.
+func matchImage(faces, ignore, detections []models.Face, iou float64, include func(models.Face) bool) []scoredDetection {
+	detections = append([]models.Face(nil), detections...)
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].Confidence > detections[j].Confidence
+	})
+
+	claimed := make([]bool, len(faces))
+	scored := make([]scoredDetection, 0, len(detections))
+	for _, detection := range detections {
+		best, bestIoU := -1, iou
+		for j, face := range faces {
+			if overlap := services.IoU(detection, face); !claimed[j] && overlap >= bestIoU && overlap > 0 {
+				best, bestIoU = j, overlap
+			}
+		}
+		if best >= 0 {
+			claimed[best] = true
+			scored = append(scored, scoredDetection{confidence: detection.Confidence, found: true})
+			continue
+		}
+
+		if coversAny(detection, ignore, iou) || (include != nil && !include(detection)) {
+			continue
+		}
+		scored = append(scored, scoredDetection{confidence: detection.Confidence})
+	}
+	return scored
+}
+
+// coversAny reports whether detection overlaps one of regions by at least iou
+func coversAny(detection models.Face, regions []models.Face, iou float64) bool {
+	for _, region := range regions {
+		if services.IoU(detection, region) >= iou {
+			return true
+		}
+	}
+	return false
+}
+
+// at returns the metrics of the detections scoring at least minConfidence
+func (c *curve) at(minConfidence float32) Metrics {
+	var m Metrics
+	for _, d := range c.detections {
+		if d.confidence < minConfidence {
+			break
+		}
+		if d.found {
+			m.TruePositives++
+		} else {
+			m.FalsePositives++
+		}
+	}
+	m.FalseNegatives = c.positives - m.TruePositives
+
+	if m.TruePositives+m.FalsePositives > 0 {
+		m.Precision = float64(m.TruePositives) / float64(m.TruePositives+m.FalsePositives)
+	}
+	if c.positives > 0 {
+		m.Recall = float64(m.TruePositives) / float64(c.positives)
+	}
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+	m.AP = c.ap
+	return m
+}
+
+// averagePrecision integrates precision over recall, taking at each recall the best
+// precision reached at that recall or beyond
+func (c *curve) averagePrecision() float64 {
+	if c.positives == 0 {
+		return 0
+	}
+
+	precisions := make([]float64, len(c.detections))
+	recalls := make([]float64, len(c.detections))
+	found := 0
+	for i, d := range c.detections {
+		if d.found {
+			found++
+		}
+		precisions[i] = float64(found) / float64(i+1)
+		recalls[i] = float64(found) / float64(c.positives)
+	}
+	for i := len(precisions) - 2; i >= 0; i-- {
+		precisions[i] = math.Max(precisions[i], precisions[i+1])
+	}
+
+	var ap, previousRecall float64
+	for i := range c.detections {
+		ap += (recalls[i] - previousRecall) * precisions[i]
+		previousRecall = recalls[i]
+	}
+	return ap
+}
+
+// summarize returns the latency distribution of durations
+func summarize(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	ms := func(d time.Duration) float64 { return d.Seconds() * 1000 }
+	percentile := func(p float64) float64 {
+		return ms(sorted[int(math.Ceil(p*float64(len(sorted))))-1])
+	}
+
+	return Latency{
+		MeanMs: ms(total) / float64(len(sorted)),
+		P50Ms:  percentile(0.5),
+		P95Ms:  percentile(0.95),
+		P99Ms:  percentile(0.99),
+		MaxMs:  ms(sorted[len(sorted)-1]),
+	}
+}
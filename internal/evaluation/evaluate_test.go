@@ -0,0 +1,131 @@
+package evaluation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"face-recognition-api/internal/models"
+)
+
+func TestAveragePrecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		found     []bool
+		positives int
+		want      float64
+	}{
+		{name: "no faces", found: []bool{false}, positives: 0, want: 0},
+		{name: "no detections", found: nil, positives: 2, want: 0},
+		{name: "every face found", found: []bool{true, true}, positives: 2, want: 1},
+		{name: "half the faces found", found: []bool{true}, positives: 2, want: 0.5},
+		{name: "false positive first", found: []bool{false, true}, positives: 1, want: 0.5},
+		{name: "false positive between", found: []bool{true, false, true}, positives: 2, want: 0.5 + 0.5*2.0/3},
+		{name: "false positives last", found: []bool{true, false, false}, positives: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &curve{positives: tt.positives}
+			for i, found := range tt.found {
+				c.detections = append(c.detections, scoredDetection{confidence: float32(len(tt.found) - i), found: found})
+			}
+			if got := c.averagePrecision(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("averagePrecision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchImage(t *testing.T) {
+	face := models.Face{X: 0, Y: 0, Width: 100, Height: 100}
+	other := models.Face{X: 200, Y: 0, Width: 100, Height: 100}
+	detection := func(x, y, size int, confidence float32) models.Face {
+		return models.Face{X: x, Y: y, Width: size, Height: size, Confidence: confidence}
+	}
+	large := func(f models.Face) bool { return f.Width >= 50 }
+
+	tests := []struct {
+		name       string
+		faces      []models.Face
+		ignore     []models.Face
+		detections []models.Face
+		iou        float64
+		include    func(models.Face) bool
+		want       []scoredDetection
+	}{
+		{
+			name:       "exact match",
+			faces:      []models.Face{face},
+			detections: []models.Face{detection(0, 0, 100, 5)},
+			iou:        0.5,
+			want:       []scoredDetection{{confidence: 5, found: true}},
+		},
+		{
+			name:       "overlap below threshold",
+			faces:      []models.Face{face},
+			detections: []models.Face{detection(60, 0, 100, 5)},
+			iou:        0.5,
+			want:       []scoredDetection{{confidence: 5}},
+		},
+		{
+			name:       "duplicate detection is a false positive",
+			faces:      []models.Face{face},
+			detections: []models.Face{detection(5, 5, 100, 3), detection(0, 0, 100, 5)},
+			iou:        0.5,
+			want:       []scoredDetection{{confidence: 5, found: true}, {confidence: 3}},
+		},
+		{
+			name:       "each detection claims its own face",
+			faces:      []models.Face{face, other},
+			detections: []models.Face{detection(200, 0, 100, 4), detection(0, 0, 100, 5)},
+			iou:        0.5,
+			want:       []scoredDetection{{confidence: 5, found: true}, {confidence: 4, found: true}},
+		},
+		{
+			name:       "detection on an ignored region is dropped",
+			faces:      []models.Face{face},
+			ignore:     []models.Face{other},
+			detections: []models.Face{detection(200, 0, 100, 5)},
+			iou:        0.5,
+			want:       []scoredDetection{},
+		},
+		{
+			name:       "unmatched detection include rejects is dropped",
+			faces:      []models.Face{face},
+			detections: []models.Face{detection(400, 0, 20, 5)},
+			iou:        0.5,
+			include:    large,
+			want:       []scoredDetection{},
+		},
+		{
+			name:       "no overlap never finds a face at zero IoU",
+			faces:      []models.Face{face},
+			detections: []models.Face{detection(400, 0, 100, 5)},
+			iou:        0,
+			want:       []scoredDetection{{confidence: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchImage(tt.faces, tt.ignore, tt.detections, tt.iou, tt.include)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchImage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIoU(t *testing.T) {
+	for _, iou := range []float64{0.01, 0.5, 1} {
+		if err := CheckIoU(iou); err != nil {
+			t.Errorf("CheckIoU(%v) = %v, want nil", iou, err)
+		}
+	}
+	for _, iou := range []float64{0, -0.5, 1.5, math.NaN()} {
+		if err := CheckIoU(iou); err == nil {
+			t.Errorf("CheckIoU(%v) = nil, want an error", iou)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package evaluation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReportFormats lists the formats a report can be written in
+var ReportFormats = []string{"json", "markdown"}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case "markdown":
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown report format %q, want one of %q", format, ReportFormats)
+	}
+}
+
+// writeMarkdown writes the report as Markdown tables
+func (r *Report) writeMarkdown(out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintf(w, "# Detection Evaluation\n\n")
+	fmt.Fprintf(w, "- Detector: %s %s\n", r.Detector.Name, r.Detector.Version)
+	fmt.Fprintf(w, "- Min confidence: %g\n", r.MinConfidence)
+	fmt.Fprintf(w, "- Images: %d (%d failed)\n", r.Images, r.ImagesFailed)
+	fmt.Fprintf(w, "- Faces: %d (%d ignored)\n", r.Faces, r.IgnoredFaces)
+	fmt.Fprintf(w, "- Detection latency: mean %.1f ms, p50 %.1f ms, p95 %.1f ms, p99 %.1f ms, max %.1f ms\n",
+		r.Latency.MeanMs, r.Latency.P50Ms, r.Latency.P95Ms, r.Latency.P99Ms, r.Latency.MaxMs)
+
+	for _, t := range r.IoUThresholds {
+		fmt.Fprintf(w, "\n## IoU %g\n\n", t.IoU)
+		fmt.Fprintf(w, "| AP | Precision | Recall | F1 | TP | FP | FN |\n")
+		fmt.Fprintf(w, "|---:|---:|---:|---:|---:|---:|---:|\n")
+		fmt.Fprintf(w, "| %.3f | %s |\n", t.AP, counts(t.Metrics))
+		fmt.Fprintf(w, "\nBest F1 %.3f at min confidence %.2f (precision %.3f, recall %.3f).\n",
+			t.BestF1.F1, t.BestF1.MinConfidence, t.BestF1.Precision, t.BestF1.Recall)
+
+		if len(t.Sizes) > 0 {
+			fmt.Fprintf(w, "\n### By face size\n\n")
+			fmt.Fprintf(w, "| Size (px) | Faces | AP | Precision | Recall | F1 | TP | FP | FN |\n")
+			fmt.Fprintf(w, "|---|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+			for _, s := range t.Sizes {
+				fmt.Fprintf(w, "| %s | %d | %.3f | %s |\n", s.Name, s.Faces, s.AP, counts(s.Metrics))
+			}
+		}
+
+		fmt.Fprintf(w, "\n### Min confidence sweep\n\n")
+		fmt.Fprintf(w, "| Min confidence | Precision | Recall | F1 | TP | FP | FN |\n")
+		fmt.Fprintf(w, "|---:|---:|---:|---:|---:|---:|---:|\n")
+		for _, p := range t.Sweep {
+			fmt.Fprintf(w, "| %.2f | %s |\n", p.MinConfidence, counts(p.Metrics))
+		}
+	}
+
+	return w.Flush()
+}
+
+// counts formats the precision, recall, F1 and count columns of a table row
+func counts(m Metrics) string {
+	return fmt.Sprintf("%.3f | %.3f | %.3f | %d | %d | %d",
+		m.Precision, m.Recall, m.F1, m.TruePositives, m.FalsePositives, m.FalseNegatives)
+}
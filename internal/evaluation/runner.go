@@ -0,0 +1,108 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"face-recognition-api/internal/services"
+)
+
+// Dataset is a set of annotated images read from Dir
+type Dataset struct {
+	Dir         string
+	Annotations []Annotation
+
+	// images and errs are set once the images are preloaded
+	images []image.Image
+	errs   []error
+}
+
+// NewDataset creates a dataset of the annotated images in dir
+func NewDataset(dir string, annotations []Annotation) *Dataset {
+	return &Dataset{Dir: dir, Annotations: annotations}
+}
+
+// Preload decodes every image into memory, so repeated runs don't read and decode them
+// again. Images that fail to load keep their error.
+func (d *Dataset) Preload(workers int) {
+	images := make([]image.Image, len(d.Annotations))
+	errs := make([]error, len(d.Annotations))
+	parallel(len(d.Annotations), workers, func(i int) {
+		images[i], errs[i] = d.load(i)
+	})
+	d.images, d.errs = images, errs
+}
+
+// Image returns the i-th image, decoding it unless the dataset is preloaded
+func (d *Dataset) Image(i int) (image.Image, error) {
+	if d.images != nil {
+		return d.images[i], d.errs[i]
+	}
+	return d.load(i)
+}
+
+// load decodes the i-th image file
+func (d *Dataset) load(i int) (image.Image, error) {
+	f, err := os.Open(filepath.Join(d.Dir, d.Annotations[i].Image))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", d.Annotations[i].Image, err)
+	}
+	return img, nil
+}
+
+// Detect runs detector on every image of the dataset with the given number of workers.
+// Durations cover detection only, not loading the image.
+func Detect(ctx context.Context, detector services.Detector, dataset *Dataset, workers int) []Result {
+	results := make([]Result, len(dataset.Annotations))
+	parallel(len(results), workers, func(i int) {
+		results[i].Image = dataset.Annotations[i].Image
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			return
+		}
+
+		img, err := dataset.Image(i)
+		if err != nil {
+			results[i].Err = err
+			return
+		}
+
+		start := time.Now()
+		results[i].Faces, results[i].Err = detector.DetectFaces(img)
+		results[i].Duration = time.Since(start)
+	})
+	return results
+}
+
+// parallel calls fn for every index below n on at most workers goroutines
+func parallel(n, workers int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
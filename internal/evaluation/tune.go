@@ -0,0 +1,154 @@
+package evaluation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"face-recognition-api/internal/config"
+)
+
+// Objectives lists the accuracy scores a parameter search can maximize
+var Objectives = []string{"f1", "ap"}
+
+// Trial is one pigo configuration tried by a parameter search. Its min confidence is the
+// one with the best F1, and Metrics are reported there.
+type Trial struct {
+	Pigo  config.PigoConfig `json:"pigo"`
+	Score float64           `json:"score"`
+	Metrics
+	Latency Latency `json:"latency"`
+	// LatencyMs is the latency the search minimizes, the mean or a percentile
+	LatencyMs float64 `json:"latency_ms"`
+	// Pareto reports whether no other trial is at least as accurate and as fast
+	Pareto bool `json:"pareto"`
+}
+
+// NewTrial scores a trial of pigo, run at min confidence zero, from its evaluation at one
+// IoU threshold. The objective is f1 or ap and latency mean, p95 or p99.
+func NewTrial(pigo config.PigoConfig, report *Report, objective, latency string) Trial {
+	best := report.IoUThresholds[0].BestF1
+	pigo.MinConfidence = best.MinConfidence
+
+	t := Trial{Pigo: pigo, Metrics: best.Metrics, Latency: report.Latency, Score: best.F1}
+	if objective == "ap" {
+		t.Score = best.AP
+	}
+	switch latency {
+	case "p95":
+		t.LatencyMs = report.Latency.P95Ms
+	case "p99":
+		t.LatencyMs = report.Latency.P99Ms
+	default:
+		t.LatencyMs = report.Latency.MeanMs
+	}
+	return t
+}
+
+// MarkPareto flags the trials on the Pareto frontier of score against latency and sorts
+// trials by score, best first, then by latency
+func MarkPareto(trials []Trial) {
+	sort.SliceStable(trials, func(i, j int) bool {
+		if trials[i].Score != trials[j].Score {
+			return trials[i].Score > trials[j].Score
+		}
+		return trials[i].LatencyMs < trials[j].LatencyMs
+	})
+
+	// Walking from the most accurate, a trial is on the frontier when it is faster than
+	// every more accurate one
+	fastest := -1.0
+	for i := range trials {
+		trials[i].Pareto = fastest < 0 || trials[i].LatencyMs < fastest
+		if trials[i].Pareto {
+			fastest = trials[i].LatencyMs
+		}
+	}
+}
+
+// Best returns the most accurate trial within the latency budget, zero meaning no budget,
+// from trials sorted by MarkPareto. It returns false when none is within budget.
+func Best(trials []Trial, maxLatencyMs float64) (Trial, bool) {
+	for _, t := range trials {
+		if maxLatencyMs <= 0 || t.LatencyMs <= maxLatencyMs {
+			return t, true
+		}
+	}
+	return Trial{}, false
+}
+
+// TuneReport is the outcome of a parameter search
+type TuneReport struct {
+	Dataset      string  `json:"dataset"`
+	Images       int     `json:"images"`
+	ImagesFailed int     `json:"images_failed"`
+	Faces        int     `json:"faces"`
+	Cascade      string  `json:"cascade"`
+	Objective    string  `json:"objective"`
+	MatchIoU     float64 `json:"match_iou"`
+	Latency      string  `json:"latency"`
+	Best         *Trial  `json:"best"`
+	Trials       []Trial `json:"trials"`
+}
+
+// Write writes the report in the given format
+func (r *TuneReport) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case "markdown":
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown report format %q, want one of %q", format, ReportFormats)
+	}
+}
+
+// writeMarkdown writes the frontier and every trial as Markdown tables
+func (r *TuneReport) writeMarkdown(out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintf(w, "# Pigo Parameter Search\n\n")
+	fmt.Fprintf(w, "- Dataset: %s (%d images, %d failed, %d faces)\n", r.Dataset, r.Images, r.ImagesFailed, r.Faces)
+	fmt.Fprintf(w, "- Cascade: %s\n", r.Cascade)
+	fmt.Fprintf(w, "- Objective: %s at IoU %g, against %s latency\n", r.Objective, r.MatchIoU, r.Latency)
+	fmt.Fprintf(w, "- Trials: %d\n", len(r.Trials))
+	if r.Best != nil {
+		fmt.Fprintf(w, "- Best: %s %.3f at %.1f ms (%s)\n", r.Objective, r.Best.Score, r.Best.LatencyMs, parameters(r.Best.Pigo))
+	}
+
+	header := func() {
+		fmt.Fprintf(w, "| Min size | Shift | Scale | IoU threshold | Min confidence | Score | Precision | Recall | Latency (ms) |\n")
+		fmt.Fprintf(w, "|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+	}
+	row := func(t Trial) {
+		fmt.Fprintf(w, "| %d | %g | %g | %g | %.2f | %.3f | %.3f | %.3f | %.1f |\n",
+			t.Pigo.MinSize, t.Pigo.ShiftFactor, t.Pigo.ScaleFactor, t.Pigo.IoUThreshold, t.Pigo.MinConfidence,
+			t.Score, t.Precision, t.Recall, t.LatencyMs)
+	}
+
+	fmt.Fprintf(w, "\n## Pareto frontier\n\n")
+	header()
+	for _, t := range r.Trials {
+		if t.Pareto {
+			row(t)
+		}
+	}
+
+	fmt.Fprintf(w, "\n## All trials\n\n")
+	header()
+	for _, t := range r.Trials {
+		row(t)
+	}
+
+	return w.Flush()
+}
+
+// parameters formats the searched parameters of a pigo configuration
+func parameters(p config.PigoConfig) string {
+	return fmt.Sprintf("min_size %d, shift_factor %g, scale_factor %g, iou_threshold %g, min_confidence %.2f",
+		p.MinSize, p.ShiftFactor, p.ScaleFactor, p.IoUThreshold, p.MinConfidence)
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"face-recognition-api/internal/auth"
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/jobs"
+	"face-recognition-api/internal/middleware"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/ratelimit"
+	"face-recognition-api/internal/services"
+)
+
+// AdminHandler handles the admin API for runtime inspection and tuning
+type AdminHandler struct {
+	reloader *config.Reloader
+	detector *services.FaceDetector
+	pool     *services.DetectionPool
+	jobs     *jobs.Manager
+	webhooks *services.WebhookDispatcher
+	authn    *middleware.Auth
+	limiter  *ratelimit.Limiter
+	inFlight *middleware.InFlight
+	logger   *logrus.Logger
+}
+
+// NewAdminHandler creates a new admin handler instance; limiter is nil when rate limiting
+// is disabled
+func NewAdminHandler(
+	reloader *config.Reloader,
+	detector *services.FaceDetector,
+	pool *services.DetectionPool,
+	jobManager *jobs.Manager,
+	webhooks *services.WebhookDispatcher,
+	authn *middleware.Auth,
+	limiter *ratelimit.Limiter,
+	inFlight *middleware.InFlight,
+	logger *logrus.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		reloader: reloader,
+		detector: detector,
+		pool:     pool,
+		jobs:     jobManager,
+		webhooks: webhooks,
+		authn:    authn,
+		limiter:  limiter,
+		inFlight: inFlight,
+		logger:   logger,
+	}
+}
+
+// LogLevelRequest represents a log level change
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// RateLimitResetRequest selects the client whose buckets are reset; an empty client resets
+// every client
+type RateLimitResetRequest struct {
+	Client string `json:"client,omitempty"`
+}
+
+// RateLimitResetResponse represents the outcome of a rate limit reset
+type RateLimitResetResponse struct {
+	Client  string `json:"client,omitempty"`
+	Buckets int    `json:"buckets"`
+}
+
+// AdminStatsResponse represents worker pool, queue and cache statistics
+type AdminStatsResponse struct {
+	DetectionPool    DetectionPoolStats      `json:"detection_pool"`
+	Jobs             JobStats                `json:"jobs"`
+	Webhooks         WebhookStats            `json:"webhooks"`
+	InFlightRequests int64                   `json:"in_flight_requests"`
+	RateLimit        *ratelimit.BackendStats `json:"rate_limit,omitempty"`
+	JWKS             []auth.KeySetStats      `json:"jwks,omitempty"`
+	Runtime          RuntimeStats            `json:"runtime"`
+}
+
+// DetectionPoolStats describes the detection worker pool
+type DetectionPoolStats struct {
+	Workers    int     `json:"workers"`
+	Busy       int     `json:"busy"`
+	Waiting    int     `json:"waiting"`
+	Saturation float64 `json:"saturation"`
+}
+
+// JobStats describes the asynchronous job queue
+type JobStats struct {
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+}
+
+// WebhookStats describes the callback delivery queue
+type WebhookStats struct {
+	Queued  int `json:"queued"`
+	Sending int `json:"sending"`
+}
+
+// RuntimeStats describes the Go runtime
+type RuntimeStats struct {
+	Goroutines     int    `json:"goroutines"`
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapObjects    uint64 `json:"heap_objects"`
+	NumGC          uint32 `json:"num_gc"`
+}
+
+// ConfigHandler handles GET /admin/config. It returns the configuration in effect, keyed
+// like the config file, with live pigo parameters and secrets redacted.
+func (h *AdminHandler) ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := *h.reloader.Current().Redacted()
+	cfg.Pigo = h.detector.Config()
+
+	// Round-trip through YAML so keys and durations match the config file
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		writeError(w, r, h.logger, models.ErrInternal.WithCause(err))
+		return
+	}
+	var view map[string]interface{}
+	if err := yaml.Unmarshal(data, &view); err != nil {
+		writeError(w, r, h.logger, models.ErrInternal.WithCause(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+// GetPigoHandler handles GET /admin/pigo
+func (h *AdminHandler) GetPigoHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.detector.Config())
+}
+
+// UpdatePigoHandler handles PATCH /admin/pigo. Fields present in the body replace the live
+// parameters until the next configuration reload.
+func (h *AdminHandler) UpdatePigoHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := h.detector.Config()
+	if err := decodeStrict(r.Body, &cfg); err != nil {
+		writeError(w, r, h.logger, models.ErrInvalidRequest.WithDetail("%v", err).WithCause(err))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		writeError(w, r, h.logger, models.ErrInvalidConfig.WithDetail("%v", err))
+		return
+	}
+
+	previous := h.detector.Config()
+	if err := h.detector.SetConfig(cfg); err != nil {
+		writeError(w, r, h.logger, models.ErrCascadeReload.WithDetail("%v", err).WithCause(err))
+		return
+	}
+
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"previous": previous,
+		"current":  cfg,
+	}).Info("Pigo parameters changed through admin API")
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// GetLogLevelHandler handles GET /admin/log-level
+func (h *AdminHandler) GetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, LogLevelRequest{Level: h.logger.GetLevel().String()})
+}
+
+// SetLogLevelHandler handles PUT /admin/log-level
+func (h *AdminHandler) SetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := decodeStrict(r.Body, &req); err != nil {
+		writeError(w, r, h.logger, models.ErrInvalidRequest.WithDetail("%v", err).WithCause(err))
+		return
+	}
+	level, err := logrus.ParseLevel(req.Level)
+	if err != nil {
+		writeError(w, r, h.logger, models.ErrInvalidLogLevel.WithDetail("%v", err))
+		return
+	}
+
+	previous := h.logger.GetLevel()
+	h.logger.SetLevel(level)
+
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"previous": previous.String(),
+		"current":  level.String(),
+	}).Warn("Log level changed through admin API")
+	writeJSON(w, http.StatusOK, LogLevelRequest{Level: level.String()})
+}
+
+// StatsHandler handles GET /admin/stats
+func (h *AdminHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	var response AdminStatsResponse
+
+	busy, waiting, workers := h.pool.Stats()
+	response.DetectionPool = DetectionPoolStats{
+		Workers:    workers,
+		Busy:       busy,
+		Waiting:    waiting,
+		Saturation: h.pool.Saturation(),
+	}
+	response.Jobs.Queued, response.Jobs.Running = h.jobs.Stats()
+	response.Webhooks.Queued, response.Webhooks.Sending = h.webhooks.Stats()
+	response.InFlightRequests = h.inFlight.Count()
+
+	if h.limiter != nil {
+		stats, err := h.limiter.Stats(r.Context())
+		if err != nil {
+			h.logger.WithContext(r.Context()).WithError(err).Warn("Failed to read rate limiter stats")
+		} else {
+			response.RateLimit = &stats
+		}
+	}
+	if _, tokens := h.authn.Credentials(); tokens != nil {
+		response.JWKS = tokens.KeyStats()
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	response.Runtime = RuntimeStats{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapObjects:    mem.HeapObjects,
+		NumGC:          mem.NumGC,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// CascadeReloadResponse lists the cascades loaded by a reload
+type CascadeReloadResponse struct {
+	Fallback string   `json:"fallback"`
+	Cascades []string `json:"cascades"`
+}
+
+// ReloadCascadeHandler handles POST /admin/cascade/reload. The cascade file and directory
+// are read and parsed again; on failure the running cascades are kept.
+func (h *AdminHandler) ReloadCascadeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.detector.ReloadCascades(); err != nil {
+		writeError(w, r, h.logger, models.ErrCascadeReload.WithDetail("%v", err).WithCause(err))
+		return
+	}
+
+	response := CascadeReloadResponse{
+		Fallback: h.detector.Fallback(),
+		Cascades: h.detector.Cascades(),
+	}
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"fallback": response.Fallback,
+		"cascades": response.Cascades,
+	}).Info("Cascades reloaded through admin API")
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ResetRateLimitHandler handles POST /admin/rate-limit/reset. It refills the token
+// buckets of one client, identified like in the limiter (key:<id>, jwt:<issuer>:<subject>
+// or ip:<address>), or of every client. Quotas are not reset.
+func (h *AdminHandler) ResetRateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if h.limiter == nil {
+		writeError(w, r, h.logger, models.ErrRateLimitOff)
+		return
+	}
+
+	var req RateLimitResetRequest
+	if err := decodeStrict(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, h.logger, models.ErrInvalidRequest.WithDetail("%v", err).WithCause(err))
+		return
+	}
+
+	reset, err := h.limiter.Reset(r.Context(), req.Client)
+	if err != nil {
+		writeError(w, r, h.logger, models.ErrRateLimitReset.WithCause(err))
+		return
+	}
+
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"client":  req.Client,
+		"buckets": reset,
+	}).Info("Rate limit buckets reset through admin API")
+	writeJSON(w, http.StatusOK, RateLimitResetResponse{Client: req.Client, Buckets: reset})
+}
+
+// ProfileHandler handles GET /admin/debug/pprof/{profile} for the named runtime profiles
+// (heap, goroutine, allocs, block, mutex, threadcreate)
+func (h *AdminHandler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(mux.Vars(r)["profile"]).ServeHTTP(w, r)
+}
+
+// decodeStrict decodes a JSON body into v, rejecting unknown fields and trailing data
+func decodeStrict(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after JSON body")
+	}
+	return nil
+}
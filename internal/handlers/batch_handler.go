@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/respond"
+)
+
+// maxURLFieldSize bounds the size of URL form fields in multipart batches
+const maxURLFieldSize = 8192
+
+// batchWriteTimeout bounds writing a batch response once its images are processed
+const batchWriteTimeout = 30 * time.Second
+
+// batchItem is one image of a batch along with any error hit while reading it from the request
+type batchItem struct {
+	ref models.ImageReference
+	err error
+}
+
+// DetectBatchHandler handles POST /api/v1/detect/batch endpoint
+func (h *FaceHandler) DetectBatchHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == ndjsonContentType {
+		h.detectBatchStream(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.batchConfig.MaxRequestSize)
+
+	items, callbackURL, err := h.parseBatchRequest(r)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.writeErrorResponse(w, r, models.ErrRequestTooLarge.WithDetail("maximum is %d bytes", maxBytesErr.Limit))
+		return
+	}
+	if err != nil {
+		h.writeErrorResponse(w, r, models.ErrInvalidBatch.WithDetail("%v", err).WithCause(err))
+		return
+	}
+
+	if !h.checkCallbackURL(w, r, callbackURL) {
+		return
+	}
+
+	if len(items) == 0 {
+		h.writeErrorResponse(w, r, models.ErrMissingImages)
+		return
+	}
+	if len(items) > h.batchConfig.MaxItems {
+		h.writeErrorResponse(w, r, models.ErrTooManyImages.WithDetail("maximum is %d images", h.batchConfig.MaxItems))
+		return
+	}
+
+	// The batch is bounded by its own timeout rather than the server's write timeout,
+	// which is already running and would drop a response finished after it
+	ctx, cancel := context.WithTimeout(r.Context(), h.batchConfig.Timeout)
+	defer cancel()
+
+	results := make([]models.BatchItemResult, len(items))
+	parallelism := max(h.batchConfig.Parallelism, 1)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+dispatch:
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// Images not started by the deadline or before the client went away fail
+			for j := i; j < len(items); j++ {
+				results[j] = h.batchItemError(ctx, j, h.unstartedError(ctx))
+			}
+			break dispatch
+		}
+
+		wg.Add(1)
+		go func(i int, item batchItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = h.processBatchItem(ctx, i, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	response := models.BatchDetectionResponse{
+		Results: results,
+		Count:   len(results),
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	response.ProcessingTimeMs = time.Since(start).Seconds() * 1000
+
+	h.notify(r.Context(), callbackURL, "batch", response, nil)
+
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"images":          response.Count,
+		"succeeded":       response.Succeeded,
+		"failed":          response.Failed,
+		"parallelism":     parallelism,
+		"processing_time": response.ProcessingTimeMs,
+	}).Info("Batch detection completed")
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchWriteTimeout))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// unstartedError is the error for batch images not started before ctx ended
+func (h *FaceHandler) unstartedError(ctx context.Context) *models.APIError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return models.ErrTimeout.WithDetail("batch timeout of %s exceeded before the image was processed", h.batchConfig.Timeout)
+	}
+	return models.ErrInternal.WithCause(ctx.Err())
+}
+
+// processBatchItem runs the detection pipeline for one batch image and records its outcome
+func (h *FaceHandler) processBatchItem(ctx context.Context, index int, item batchItem) models.BatchItemResult {
+	start := time.Now()
+
+	err := item.err
+	var response *models.FaceDetectionResponse
+	if err == nil {
+		itemCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		response, err = h.detectImage(itemCtx, item.ref, start)
+		cancel()
+	}
+
+	if err != nil {
+		return h.batchItemError(ctx, index, err)
+	}
+
+	return models.BatchItemResult{Index: index, Result: response}
+}
+
+// batchItemError records the failure of one batch image
+func (h *FaceHandler) batchItemError(ctx context.Context, index int, err error) models.BatchItemResult {
+	h.logger.WithContext(ctx).WithError(err).WithField("index", index).Warn("Batch item failed")
+
+	apiErr := respond.AsAPIError(err)
+	metrics.Errors.WithLabelValues(apiErr.Code).Inc()
+	return models.BatchItemResult{Index: index, Error: apiErr}
+}
+
+// parseBatchRequest reads image references and the optional callback URL from a JSON body
+// or from multipart/form-data parts
+func (h *FaceHandler) parseBatchRequest(r *http.Request) ([]batchItem, string, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		return h.parseMultipartBatch(r)
+	}
+
+	var req models.BatchDetectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, "", err
+	}
+
+	items := make([]batchItem, len(req.Images))
+	for i, ref := range req.Images {
+		items[i] = batchItem{ref: ref}
+	}
+	inheritSelection(items, req.DetectorSelection)
+	return items, req.CallbackURL, nil
+}
+
+// inheritSelection applies the batch's detector selection to images without their own
+func inheritSelection(items []batchItem, sel models.DetectorSelection) {
+	for i := range items {
+		if items[i].ref.DetectorSelection == (models.DetectorSelection{}) {
+			items[i].ref.DetectorSelection = sel
+		}
+	}
+}
+
+// parseMultipartBatch treats every file part as an uploaded image, every image_url field as a URL,
+// a callback_url field as the callback URL and detector, cascade or profile fields as the
+// detector selection for every image
+func (h *FaceHandler) parseMultipartBatch(r *http.Request) ([]batchItem, string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []batchItem
+	var callbackURL string
+	var sel models.DetectorSelection
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			inheritSelection(items, sel)
+			return items, callbackURL, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Stop reading once the batch is too large; the caller rejects it
+		if len(items) > h.batchConfig.MaxItems {
+			part.Close()
+			return items, callbackURL, nil
+		}
+
+		switch {
+		case part.FileName() != "":
+			data, err := h.imageDownloader.ReadImage(part)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, "", err
+			}
+			if err != nil {
+				err = imageError(err)
+			}
+			items = append(items, batchItem{ref: models.ImageReference{Data: data}, err: err})
+
+		case part.FormName() == "image_url":
+			value, err := readFormField(part)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, batchItem{ref: models.ImageReference{ImageURL: value}})
+
+		case part.FormName() == "callback_url":
+			if callbackURL, err = readFormField(part); err != nil {
+				return nil, "", err
+			}
+
+		case part.FormName() == "detector":
+			if sel.Detector, err = readFormField(part); err != nil {
+				return nil, "", err
+			}
+
+		case part.FormName() == "cascade":
+			if sel.Cascade, err = readFormField(part); err != nil {
+				return nil, "", err
+			}
+
+		case part.FormName() == "profile":
+			if sel.Profile, err = readFormField(part); err != nil {
+				return nil, "", err
+			}
+		}
+
+		part.Close()
+	}
+}
+
+// readFormField reads a short text form field, failing rather than truncating one longer
+// than maxURLFieldSize
+func readFormField(part *multipart.Part) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, maxURLFieldSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxURLFieldSize {
+		return "", fmt.Errorf("form field %s is longer than %d bytes", part.FormName(), maxURLFieldSize)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
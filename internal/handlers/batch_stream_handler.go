@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+)
+
+// ndjsonContentType is the media type for newline-delimited JSON batch streams
+const ndjsonContentType = "application/x-ndjson"
+
+// streamErrorIndex marks result lines that report a stream-level error rather than one image
+const streamErrorIndex = -1
+
+// detectBatchStream processes an NDJSON stream of image references and writes one NDJSON result
+// line per image as soon as it completes. At most Parallelism images are read and processed at
+// a time, so memory stays bounded no matter how long the stream is. Results arrive out of order
+// and carry their input index so clients can re-order them.
+func (h *FaceHandler) detectBatchStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeErrorResponse(w, r, models.ErrStreamingUnsupported)
+		return
+	}
+
+	// HTTP/1 servers stop reading the request body once the response has started unless
+	// full duplex is enabled; HTTP/2 always supports it
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.writeErrorResponse(w, r, models.ErrStreamingUnsupported.WithCause(err))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	parallelism := max(h.batchConfig.Parallelism, 1)
+	results := make(chan models.BatchItemResult, parallelism)
+
+	go h.readBatchStream(ctx, rc, r, parallelism, results)
+
+	encoder := json.NewEncoder(w)
+	var count, failed int
+	writeFailed := false
+	for result := range results {
+		// Keep draining after a write failure so in-flight workers can finish
+		if writeFailed {
+			continue
+		}
+
+		rc.SetWriteDeadline(time.Now().Add(h.batchConfig.StreamIdleTimeout))
+		if err := encoder.Encode(result); err != nil {
+			h.logger.WithContext(ctx).WithError(err).Warn("Failed to write batch stream result")
+			writeFailed = true
+			cancel()
+			continue
+		}
+		flusher.Flush()
+
+		if result.Index != streamErrorIndex {
+			count++
+		}
+		if result.Error != nil {
+			failed++
+		}
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"images":          count,
+		"failed":          failed,
+		"parallelism":     parallelism,
+		"processing_time": time.Since(start).Seconds() * 1000,
+	}).Info("Batch stream completed")
+}
+
+// readBatchStream reads image references line by line and hands each to a worker, blocking
+// while parallelism workers are busy. It closes results once every worker has reported.
+func (h *FaceHandler) readBatchStream(
+	ctx context.Context,
+	rc *http.ResponseController,
+	r *http.Request,
+	parallelism int,
+	results chan<- models.BatchItemResult,
+) {
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	defer func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), h.batchConfig.StreamMaxLineSize)
+
+	index := 0
+	for {
+		rc.SetReadDeadline(time.Now().Add(h.batchConfig.StreamIdleTimeout))
+		if !scanner.Scan() {
+			break
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var item batchItem
+		if err := json.Unmarshal(line, &item.ref); err != nil {
+			item.err = models.ErrInvalidRequest.WithDetail("invalid JSON line: %v", err).WithCause(err)
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		wg.Add(1)
+		go func(i int, item batchItem) {
+			defer wg.Done()
+			results <- h.processBatchItem(ctx, i, item)
+			<-sem
+		}(index, item)
+		index++
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		h.logger.WithContext(ctx).WithError(err).WithField("images_read", index).Warn("Failed to read batch stream")
+		metrics.Errors.WithLabelValues(models.ErrInvalidStream.Code).Inc()
+		results <- models.BatchItemResult{
+			Index: streamErrorIndex,
+			Error: models.ErrInvalidStream.WithDetail("%v", err),
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/services"
+)
+
+// DetectorsResponse lists the detection backends and profiles requests can select
+type DetectorsResponse struct {
+	Default   string                              `json:"default"`
+	Detectors []DetectorDescription               `json:"detectors"`
+	Profiles  map[string]models.DetectorSelection `json:"profiles"`
+}
+
+// DetectorDescription describes one detection backend
+type DetectorDescription struct {
+	Name         string                        `json:"name"`
+	Version      string                        `json:"version"`
+	Capabilities services.DetectorCapabilities `json:"capabilities"`
+	Cascades     []string                      `json:"cascades,omitempty"`
+}
+
+// DetectorsHandler handles GET /api/v1/detectors endpoint
+func (h *FaceHandler) DetectorsHandler(w http.ResponseWriter, r *http.Request) {
+	response := DetectorsResponse{
+		Default:  h.detectors.Default().Name(),
+		Profiles: h.detectors.Profiles(),
+	}
+	for _, detector := range h.detectors.Detectors() {
+		description := DetectorDescription{
+			Name:         detector.Name(),
+			Version:      detector.Version(),
+			Capabilities: detector.Capabilities(),
+		}
+		if cascades, ok := detector.(services.CascadeSelector); ok {
+			description.Cascades = cascades.Cascades()
+		}
+		response.Detectors = append(response.Detectors, description)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
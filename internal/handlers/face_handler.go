@@ -2,35 +2,60 @@ package handlers
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"image"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/jobs"
+	"face-recognition-api/internal/metrics"
 	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/respond"
 	"face-recognition-api/internal/services"
+	"face-recognition-api/internal/tracing"
 )
 
 // FaceHandler handles face detection related endpoints
 type FaceHandler struct {
-	faceDetector    *services.FaceDetector
+	detectors       *services.DetectorRegistry
+	detectionPool   *services.DetectionPool
 	imageDownloader *services.ImageDownloader
 	imageProcessor  *services.ImageProcessor
+	webhooks        *services.WebhookDispatcher
+	shadow          *services.ShadowRunner
+	batchConfig     config.BatchConfig
 	logger          *logrus.Logger
 }
 
-// NewFaceHandler creates a new face handler instance
+// NewFaceHandler creates a new face handler instance; shadow is nil when shadow detection
+// is disabled
 func NewFaceHandler(
-	fd *services.FaceDetector,
+	detectors *services.DetectorRegistry,
+	pool *services.DetectionPool,
 	id *services.ImageDownloader,
 	ip *services.ImageProcessor,
+	webhooks *services.WebhookDispatcher,
+	shadow *services.ShadowRunner,
+	batchCfg config.BatchConfig,
 	logger *logrus.Logger,
 ) *FaceHandler {
 	return &FaceHandler{
-		faceDetector:    fd,
+		detectors:       detectors,
+		detectionPool:   pool,
 		imageDownloader: id,
 		imageProcessor:  ip,
+		webhooks:        webhooks,
+		shadow:          shadow,
+		batchConfig:     batchCfg,
 		logger:          logger,
 	}
 }
@@ -41,62 +66,105 @@ func (h *FaceHandler) DetectHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req models.FaceDetectionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid JSON request", err)
+		h.writeErrorResponse(w, r, models.ErrInvalidRequest.WithCause(err))
 		return
 	}
 
 	// Validate request
 	if req.ImageURL == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "MISSING_IMAGE_URL", "Image URL is required", nil)
+		h.writeErrorResponse(w, r, models.ErrMissingImageURL)
+		return
+	}
+
+	if !h.checkCallbackURL(w, r, req.CallbackURL) {
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	// Download image
-	img, metadata, err := h.imageDownloader.DownloadImage(ctx, req.ImageURL)
+	ref := models.ImageReference{ImageURL: req.ImageURL, DetectorSelection: req.DetectorSelection}
+	response, err := h.detectImage(ctx, ref, start)
+	h.notify(ctx, req.CallbackURL, "detect", response, err)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "IMAGE_DOWNLOAD_FAILED", "Failed to download image", err)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
-	// Detect faces
-	faces, err := h.faceDetector.DetectFaces(img)
-	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "FACE_DETECTION_FAILED", "Face detection failed", err)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ValidateHandler handles POST /api/v1/validate endpoint
+func (h *FaceHandler) ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req models.SelfieValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, r, models.ErrInvalidRequest.WithCause(err))
 		return
 	}
 
-	processingTime := time.Since(start).Seconds() * 1000
+	// Validate request
+	if req.ImageURL == "" {
+		h.writeErrorResponse(w, r, models.ErrMissingImageURL)
+		return
+	}
 
-	response := models.FaceDetectionResponse{
-		Faces:            faces,
-		Count:            len(faces),
-		ImageMetadata:    metadata,
-		ProcessingTimeMs: processingTime,
+	if !h.checkCallbackURL(w, r, req.CallbackURL) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.validateSelfie(ctx, req, start)
+	h.notify(ctx, req.CallbackURL, "validate", response, err)
+	if err != nil {
+		h.writeErrorResponse(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// ValidateHandler handles POST /api/v1/validate endpoint
-func (h *FaceHandler) ValidateHandler(w http.ResponseWriter, r *http.Request) {
+// DetectVisualHandler handles POST /api/v1/detect-visual endpoint
+func (h *FaceHandler) DetectVisualHandler(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
-	var req models.SelfieValidationRequest
+	var req models.VisualDetectionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid JSON request", err)
+		h.writeErrorResponse(w, r, models.ErrInvalidRequest.WithCause(err))
 		return
 	}
 
 	// Validate request
 	if req.ImageURL == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "MISSING_IMAGE_URL", "Image URL is required", nil)
+		h.writeErrorResponse(w, r, models.ErrMissingImageURL)
 		return
 	}
 
+	if !h.checkCallbackURL(w, r, req.CallbackURL) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.detectVisual(ctx, req, start)
+	h.notify(ctx, req.CallbackURL, "visual", response, err)
+	if err != nil {
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateSelfie runs the selfie validation pipeline, applying request defaults
+func (h *FaceHandler) validateSelfie(ctx context.Context, req models.SelfieValidationRequest, start time.Time) (*models.SelfieValidationResponse, error) {
 	// Set defaults
 	if req.MinFaces == 0 {
 		req.MinFaces = 1
@@ -105,53 +173,61 @@ func (h *FaceHandler) ValidateHandler(w http.ResponseWriter, r *http.Request) {
 		req.MaxFaces = 1
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
+	detector, err := h.selectDetector(req.DetectorSelection)
+	if err != nil {
+		return nil, err
+	}
 
-	// Download image
-	img, _, err := h.imageDownloader.DownloadImage(ctx, req.ImageURL)
+	img, _, err := h.loadImage(ctx, models.ImageReference{ImageURL: req.ImageURL})
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "IMAGE_DOWNLOAD_FAILED", "Failed to download image", err)
-		return
+		return nil, err
 	}
 
 	// Detect faces
-	faces, err := h.faceDetector.DetectFaces(img)
+	faces, err := h.detectFaces(ctx, detector, img)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "FACE_DETECTION_FAILED", "Face detection failed", err)
-		return
+		return nil, err
 	}
 
 	// Validate selfie
-	response := h.faceDetector.ValidateSelfie(faces, req.MinFaces, req.MaxFaces)
-
-	h.logger.WithFields(logrus.Fields{
+	_, span := tracing.Start(ctx, "ValidateSelfie",
+		attribute.Int("selfie.min_faces", req.MinFaces),
+		attribute.Int("selfie.max_faces", req.MaxFaces),
+	)
+	response := services.ValidateSelfie(faces, req.MinFaces, req.MaxFaces, detector.Capabilities().LowConfidence)
+	response.Detector = detectorInfo(detector, req.DetectorSelection)
+	recordSelfieValidation(response)
+	span.SetAttributes(
+		attribute.Bool("selfie.is_valid", response.IsValid),
+		attribute.StringSlice("selfie.issue_codes", response.IssueCodes),
+	)
+	span.End()
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"url":             req.ImageURL,
+		"detector":        detector.Name(),
 		"faces_detected":  len(faces),
 		"is_valid":        response.IsValid,
 		"processing_time": time.Since(start).Milliseconds(),
 	}).Info("Selfie validation completed")
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return &response, nil
 }
 
-// DetectVisualHandler handles POST /api/v1/detect-visual endpoint
-func (h *FaceHandler) DetectVisualHandler(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-
-	var req models.VisualDetectionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid JSON request", err)
-		return
+// recordSelfieValidation counts a validation outcome and its issues
+func recordSelfieValidation(response models.SelfieValidationResponse) {
+	outcome := "valid"
+	if !response.IsValid {
+		outcome = "invalid"
 	}
-
-	// Validate request
-	if req.ImageURL == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "MISSING_IMAGE_URL", "Image URL is required", nil)
-		return
+	metrics.SelfieValidations.WithLabelValues(outcome).Inc()
+	for _, code := range response.IssueCodes {
+		metrics.SelfieValidationIssues.WithLabelValues(code).Inc()
 	}
+}
 
+// prepareVisualRequest applies visual detection defaults and resolves the circle options
+func prepareVisualRequest(req *models.VisualDetectionRequest) (services.CircleOptions, error) {
 	// Set defaults
 	if req.CircleColor == "" {
 		req.CircleColor = "red"
@@ -159,73 +235,308 @@ func (h *FaceHandler) DetectVisualHandler(w http.ResponseWriter, r *http.Request
 	if req.LineWidth == 0 {
 		req.LineWidth = 3
 	}
+	if req.OutputFormat == "" {
+		req.OutputFormat = models.OutputFormatImage
+	}
+	if req.OutputFormat != models.OutputFormatImage && req.OutputFormat != models.OutputFormatSVG {
+		return services.CircleOptions{}, models.ErrInvalidOutputFormat.WithDetail("unsupported output format: %s", req.OutputFormat)
+	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
+	circleColor, err := services.ParseColor(req.CircleColor)
+	if err != nil {
+		return services.CircleOptions{}, models.ErrInvalidColor.WithDetail("%v", err)
+	}
+
+	return services.CircleOptions{
+		Color:     circleColor,
+		LineWidth: req.LineWidth,
+	}, nil
+}
 
-	// Download image
-	img, metadata, err := h.imageDownloader.DownloadImage(ctx, req.ImageURL)
+// detectVisual runs the visual detection pipeline and renders either an annotated image or an SVG overlay
+func (h *FaceHandler) detectVisual(ctx context.Context, req models.VisualDetectionRequest, start time.Time) (*models.VisualDetectionResponse, error) {
+	circleOpts, err := prepareVisualRequest(&req)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "IMAGE_DOWNLOAD_FAILED", "Failed to download image", err)
-		return
+		return nil, err
 	}
 
-	// Detect faces
-	faces, err := h.faceDetector.DetectFaces(img)
+	detector, err := h.selectDetector(req.DetectorSelection)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "FACE_DETECTION_FAILED", "Face detection failed", err)
-		return
+		return nil, err
 	}
 
-	// Parse color and create circle options
-	circleColor := h.imageProcessor.ParseColor(req.CircleColor)
-	circleOpts := services.CircleOptions{
-		Color:     circleColor,
-		LineWidth: req.LineWidth,
+	img, metadata, err := h.loadImage(ctx, models.ImageReference{ImageURL: req.ImageURL})
+	if err != nil {
+		return nil, err
 	}
 
-	// Draw circles on image
-	imageBase64, err := h.imageProcessor.DrawFaceCircles(img, faces, circleOpts)
+	// Detect faces
+	faces, err := h.detectFaces(ctx, detector, img)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "IMAGE_PROCESSING_FAILED", "Failed to process image", err)
-		return
+		return nil, err
 	}
 
-	processingTime := time.Since(start).Seconds() * 1000
+	response := &models.VisualDetectionResponse{
+		Faces:         faces,
+		Count:         len(faces),
+		ImageMetadata: metadata,
+		Detector:      detectorInfo(detector, req.DetectorSelection),
+	}
 
-	response := models.VisualDetectionResponse{
-		ImageBase64:      imageBase64,
-		Faces:            faces,
-		Count:            len(faces),
-		ImageMetadata:    metadata,
-		ProcessingTimeMs: processingTime,
+	if req.OutputFormat == models.OutputFormatSVG {
+		// Return a vector overlay only; the client already has the original image
+		landmarkCtx, span := tracing.Start(ctx, "LocateLandmarks", attribute.Int("faces.count", len(faces)))
+		faces, err = h.detectionPool.LocateLandmarks(landmarkCtx, img, faces)
+		tracing.End(span, err)
+		if apiErr := poolError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		if err != nil {
+			return nil, models.ErrImageProcessing.WithCause(err)
+		}
+		response.Faces = faces
+
+		spanCtx, span := tracing.Start(ctx, "RenderSVGOverlay")
+		response.SVGOverlay = h.imageProcessor.RenderSVGOverlay(spanCtx, faces, metadata, circleOpts)
+		span.End()
+	} else {
+		// Draw circles on image
+		spanCtx, span := tracing.Start(ctx, "DrawFaceCircles", attribute.Int("faces.count", len(faces)))
+		imageBase64, err := h.imageProcessor.DrawFaceCircles(spanCtx, img, faces, circleOpts)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, models.ErrImageProcessing.WithCause(err)
+		}
+		response.ImageBase64 = imageBase64
 	}
 
-	h.logger.WithFields(logrus.Fields{
+	processingTime := time.Since(start).Seconds() * 1000
+	response.ProcessingTimeMs = processingTime
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"url":             req.ImageURL,
+		"detector":        detector.Name(),
 		"faces_detected":  len(faces),
 		"circle_color":    req.CircleColor,
 		"line_width":      req.LineWidth,
+		"output_format":   req.OutputFormat,
 		"processing_time": processingTime,
 	}).Info("Visual detection completed")
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return response, nil
 }
 
-// writeErrorResponse writes a structured error response
-func (h *FaceHandler) writeErrorResponse(w http.ResponseWriter, status int, code, message string, err error) {
+// loadImage obtains the referenced image and reports the load stage as done
+func (h *FaceHandler) loadImage(ctx context.Context, ref models.ImageReference) (image.Image, models.ImageMetadata, error) {
+	img, metadata, err := h.loadImageSource(ctx, ref)
 	if err != nil {
-		h.logger.WithError(err).Error(message)
+		return nil, models.ImageMetadata{}, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	jobs.ReportProgress(ctx, 0.5)
+	return img, metadata, nil
+}
 
-	errorResp := map[string]interface{}{
-		"error": message,
-		"code":  code,
+// loadImageSource obtains the referenced image from its URL, inline base64 data or uploaded bytes
+func (h *FaceHandler) loadImageSource(ctx context.Context, ref models.ImageReference) (image.Image, models.ImageMetadata, error) {
+	switch {
+	case ref.Data != nil:
+		img, metadata, err := h.imageDownloader.DecodeImage(ctx, ref.Data)
+		if err != nil {
+			return nil, models.ImageMetadata{}, imageError(err)
+		}
+		return img, metadata, nil
+
+	case ref.ImageBase64 != "":
+		data, err := decodeBase64Image(ref.ImageBase64)
+		if err != nil {
+			return nil, models.ImageMetadata{}, models.ErrInvalidImageData.WithCause(err)
+		}
+		img, metadata, err := h.imageDownloader.DecodeImage(ctx, data)
+		if err != nil {
+			return nil, models.ImageMetadata{}, imageError(err)
+		}
+		return img, metadata, nil
+
+	case ref.ImageURL != "":
+		spanCtx, span := tracing.Start(ctx, "DownloadImage")
+		img, metadata, err := h.imageDownloader.DownloadImage(spanCtx, ref.ImageURL)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, models.ImageMetadata{}, imageError(err)
+		}
+		return img, metadata, nil
 	}
 
-	json.NewEncoder(w).Encode(errorResp)
-}
\ No newline at end of file
+	return nil, models.ImageMetadata{}, models.ErrMissingImage
+}
+
+// imageError maps an image retrieval or decoding failure to the API error returned to clients.
+// Client-side problems carry the failure description as detail; upstream failures do not, so
+// transport details stay in the logs.
+func imageError(err error) *models.APIError {
+	apiErr := models.ErrImageDownload
+	switch {
+	case errors.Is(err, services.ErrInvalidURL):
+		apiErr = models.ErrInvalidURL
+	case errors.Is(err, services.ErrURLNotAllowed):
+		apiErr = models.ErrURLNotAllowed
+	case errors.Is(err, services.ErrImageNotFound):
+		apiErr = models.ErrImageNotFound
+	case errors.Is(err, services.ErrDownloadTimeout):
+		apiErr = models.ErrDownloadTimeout
+	case errors.Is(err, services.ErrImageTooLarge):
+		apiErr = models.ErrImageTooLarge
+	case errors.Is(err, services.ErrImageDimensions):
+		apiErr = models.ErrImageDimensions
+	case errors.Is(err, services.ErrUnsupportedFormat):
+		apiErr = models.ErrImageFormat
+	case errors.Is(err, services.ErrCorruptImage):
+		apiErr = models.ErrImageDecode
+	}
+
+	if apiErr.Status < http.StatusInternalServerError {
+		apiErr = apiErr.WithDetail("%v", err)
+	}
+	return apiErr.WithCause(err)
+}
+
+// selectDetector resolves the detection backend a request selects
+func (h *FaceHandler) selectDetector(sel models.DetectorSelection) (services.Detector, error) {
+	detector, err := h.detectors.Select(sel)
+	if err != nil {
+		return nil, models.ErrInvalidDetector.WithDetail("%v", err)
+	}
+	return detector, nil
+}
+
+// detectorInfo identifies the backend that served a request, and the profile that selected it
+func detectorInfo(detector services.Detector, sel models.DetectorSelection) models.DetectorInfo {
+	return models.DetectorInfo{
+		Name:    detector.Name(),
+		Version: detector.Version(),
+		Profile: sel.Profile,
+	}
+}
+
+// detectFaces runs face detection with detector on a loaded image through the bounded
+// detection pool
+func (h *FaceHandler) detectFaces(ctx context.Context, detector services.Detector, img image.Image) ([]models.Face, error) {
+	spanCtx, span := tracing.Start(ctx, "DetectFaces", attribute.String("detector.name", detector.Name()))
+	faces, err := h.detectionPool.DetectFaces(spanCtx, detector, img)
+	span.SetAttributes(attribute.Int("faces.count", len(faces)))
+	tracing.End(span, err)
+	if apiErr := poolError(err); apiErr != nil {
+		return nil, apiErr
+	}
+	if errors.Is(err, services.ErrUnknownCascade) {
+		return nil, models.ErrInvalidDetector.WithDetail("%v", err)
+	}
+	if err != nil {
+		return nil, models.ErrFaceDetection.WithCause(err)
+	}
+	if h.shadow != nil {
+		h.shadow.Observe(ctx, detector, img, faces)
+	}
+
+	jobs.ReportProgress(ctx, 0.8)
+	return faces, nil
+}
+
+// poolError maps a detection pool rejection to its API error, or returns nil when err isn't one
+func poolError(err error) error {
+	if errors.Is(err, services.ErrDetectorBusy) {
+		return models.ErrServerBusy.WithCause(err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The request's own deadline ran out while waiting for a worker; retrying won't help
+		return models.ErrTimeout.WithCause(err)
+	}
+	return nil
+}
+
+// detectImage runs the load and detection stages for one image reference with the backend
+// it selects
+func (h *FaceHandler) detectImage(ctx context.Context, ref models.ImageReference, start time.Time) (*models.FaceDetectionResponse, error) {
+	detector, err := h.selectDetector(ref.DetectorSelection)
+	if err != nil {
+		return nil, err
+	}
+
+	img, metadata, err := h.loadImage(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	faces, err := h.detectFaces(ctx, detector, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FaceDetectionResponse{
+		Faces:            faces,
+		Count:            len(faces),
+		ImageMetadata:    metadata,
+		Detector:         detectorInfo(detector, ref.DetectorSelection),
+		ProcessingTimeMs: time.Since(start).Seconds() * 1000,
+	}, nil
+}
+
+// decodeBase64Image decodes base64 image data, with or without a data URL prefix
+func decodeBase64Image(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		_, payload, found := strings.Cut(value, ",")
+		if !found {
+			return nil, errors.New("malformed data URL")
+		}
+		value = payload
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+}
+
+// checkCallbackURL validates an optional callback URL, writing a 400 response when it is rejected
+func (h *FaceHandler) checkCallbackURL(w http.ResponseWriter, r *http.Request, callbackURL string) bool {
+	if callbackURL == "" {
+		return true
+	}
+
+	err := h.webhooks.ValidateCallbackURL(callbackURL)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrCallbacksDisabled):
+		h.writeErrorResponse(w, r, models.ErrCallbacksDisabled)
+	default:
+		h.writeErrorResponse(w, r, models.ErrInvalidCallbackURL.WithDetail("%v", err).WithCause(err))
+	}
+	return false
+}
+
+// notify queues a "<kind>.completed" or "<kind>.failed" callback when the request asked for one
+func (h *FaceHandler) notify(ctx context.Context, callbackURL, kind string, result interface{}, err error) {
+	if callbackURL == "" {
+		return
+	}
+
+	var deliverErr error
+	if err != nil {
+		deliverErr = h.webhooks.Deliver(ctx, callbackURL, kind+".failed", nil, respond.AsAPIError(err))
+	} else {
+		deliverErr = h.webhooks.Deliver(ctx, callbackURL, kind+".completed", result, nil)
+	}
+
+	if deliverErr != nil {
+		h.logger.WithContext(ctx).WithError(deliverErr).WithField("event", kind).Warn("Failed to queue callback")
+	}
+}
+
+// writeErrorResponse writes the structured error response for err, asking clients to back
+// off when the server is busy
+func (h *FaceHandler) writeErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrServerBusy) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(h.detectionPool.RetryAfter().Seconds()))))
+	}
+
+	writeError(w, r, h.logger, err)
+}
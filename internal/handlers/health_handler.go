@@ -6,17 +6,21 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/health"
 )
 
 // HealthHandler handles health check endpoints
 type HealthHandler struct {
+	checker   *health.Checker
 	logger    *logrus.Logger
 	startTime time.Time
 }
 
 // NewHealthHandler creates a new health handler instance
-func NewHealthHandler(logger *logrus.Logger) *HealthHandler {
+func NewHealthHandler(checker *health.Checker, logger *logrus.Logger) *HealthHandler {
 	return &HealthHandler{
+		checker:   checker,
 		logger:    logger,
 		startTime: time.Now(),
 	}
@@ -31,6 +35,13 @@ type HealthResponse struct {
 	Service   string    `json:"service"`
 }
 
+// ReadinessResponse represents the readiness check response
+type ReadinessResponse struct {
+	Status    string                        `json:"status"`
+	Timestamp time.Time                     `json:"timestamp"`
+	Checks    map[string]health.CheckResult `json:"checks"`
+}
+
 // HealthHandler handles GET /api/v1/health endpoint
 func (h *HealthHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(h.startTime)
@@ -48,22 +59,28 @@ func (h *HealthHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// ReadinessHandler handles GET /api/v1/ready endpoint
+// ReadinessHandler handles GET /api/v1/ready endpoint. It reports the latest result of every
+// readiness check and answers 503 until all of them pass.
 func (h *HealthHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
-	// In a real application, you would check dependencies here
-	// For now, we'll just return ready
-	response := map[string]interface{}{
-		"status":    "ready",
-		"timestamp": time.Now(),
-		"checks": map[string]string{
-			"pigo":      "ok",
-			"memory":    "ok",
-			"disk":      "ok",
-		},
+	ready, checks := h.checker.Ready()
+
+	response := ReadinessResponse{
+		Status:    "ready",
+		Timestamp: time.Now(),
+		Checks:    checks,
+	}
+	status := http.StatusOK
+	switch {
+	case h.checker.Draining():
+		response.Status = "draining"
+		status = http.StatusServiceUnavailable
+	case !ready:
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/auth"
+	"face-recognition-api/internal/jobs"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/services"
+)
+
+// jobScopes maps job types to the scope needed to submit them
+var jobScopes = map[string]auth.Scope{
+	jobs.TypeDetect:   auth.ScopeDetect,
+	jobs.TypeValidate: auth.ScopeValidate,
+	jobs.TypeVisual:   auth.ScopeVisual,
+}
+
+// JobHandler handles asynchronous job endpoints
+type JobHandler struct {
+	manager *jobs.Manager
+	logger  *logrus.Logger
+}
+
+// NewJobHandler creates a new job handler instance
+func NewJobHandler(manager *jobs.Manager, logger *logrus.Logger) *JobHandler {
+	return &JobHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// CreateHandler handles POST /api/v1/jobs endpoint
+func (h *JobHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, h.logger, models.ErrInvalidRequest.WithCause(err))
+		return
+	}
+
+	// The route only requires authentication; the scope depends on the job type
+	if identity := auth.FromContext(r.Context()); identity != nil {
+		if scope, ok := jobScopes[req.Type]; ok && !identity.HasScope(scope) {
+			writeError(w, r, h.logger, models.ErrForbidden.WithDetail("requires scope %s", scope))
+			return
+		}
+	}
+
+	job, err := h.manager.Submit(r.Context(), req.Type, req.Request, req.CallbackURL)
+	if err != nil {
+		var apiErr *models.APIError
+		switch {
+		case errors.Is(err, services.ErrCallbacksDisabled):
+			writeError(w, r, h.logger, models.ErrCallbacksDisabled)
+		case errors.Is(err, services.ErrInvalidCallbackURL):
+			writeError(w, r, h.logger, models.ErrInvalidCallbackURL.WithDetail("%v", err).WithCause(err))
+		case errors.Is(err, jobs.ErrUnknownJobType):
+			writeError(w, r, h.logger, models.ErrInvalidJobType)
+		case errors.As(err, &apiErr):
+			writeError(w, r, h.logger, apiErr)
+		case errors.Is(err, jobs.ErrQueueFull):
+			w.Header().Set("Retry-After", "5")
+			writeError(w, r, h.logger, models.ErrJobQueueFull)
+		case errors.Is(err, jobs.ErrShuttingDown):
+			writeError(w, r, h.logger, models.ErrShuttingDown)
+		default:
+			writeError(w, r, h.logger, models.ErrJobCreateFailed.WithCause(err))
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetHandler handles GET /api/v1/jobs/{id} endpoint
+func (h *JobHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := h.lookup(r)
+	if err != nil {
+		h.writeLookupError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// CancelHandler handles DELETE /api/v1/jobs/{id} endpoint
+func (h *JobHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := h.lookup(r)
+	if err == nil {
+		job, err = h.manager.Cancel(r.Context(), job.ID)
+	}
+	if errors.Is(err, jobs.ErrJobFinished) {
+		writeError(w, r, h.logger, models.ErrJobFinished.WithDetail("job is already %s", job.Status))
+		return
+	}
+	if err != nil {
+		h.writeLookupError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// lookup loads the job named in the URL. Jobs owned by another client are reported as not
+// found so clients cannot probe for other clients' job IDs; admins can see every job.
+func (h *JobHandler) lookup(r *http.Request) (*jobs.Job, error) {
+	job, err := h.manager.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	identity := auth.FromContext(r.Context())
+	if identity != nil && job.Owner != identity.Principal() && !identity.HasScope(auth.ScopeAdmin) {
+		return nil, jobs.ErrJobNotFound
+	}
+	return job, nil
+}
+
+// writeLookupError maps job store errors to responses
+func (h *JobHandler) writeLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		writeError(w, r, h.logger, models.ErrJobNotFound)
+		return
+	}
+	writeError(w, r, h.logger, models.ErrJobLookupFailed.WithCause(err))
+}
+
+// ValidateJob checks a queued job request the same way the synchronous endpoints do
+func (h *FaceHandler) ValidateJob(jobType string, request json.RawMessage) error {
+	var req models.VisualDetectionRequest
+	if err := json.Unmarshal(request, &req); err != nil {
+		return models.ErrInvalidRequest.WithCause(err)
+	}
+
+	if req.ImageURL == "" {
+		return models.ErrMissingImageURL
+	}
+
+	if jobType == jobs.TypeVisual {
+		if _, err := prepareVisualRequest(&req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessJob runs a queued job through the same pipeline as the synchronous endpoints
+func (h *FaceHandler) ProcessJob(ctx context.Context, jobType string, request json.RawMessage) (interface{}, error) {
+	start := time.Now()
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch jobType {
+	case jobs.TypeDetect:
+		var req models.FaceDetectionRequest
+		if err = json.Unmarshal(request, &req); err == nil {
+			ref := models.ImageReference{ImageURL: req.ImageURL, DetectorSelection: req.DetectorSelection}
+			result, err = h.detectImage(ctx, ref, start)
+		}
+	case jobs.TypeValidate:
+		var req models.SelfieValidationRequest
+		if err = json.Unmarshal(request, &req); err == nil {
+			result, err = h.validateSelfie(ctx, req, start)
+		}
+	case jobs.TypeVisual:
+		var req models.VisualDetectionRequest
+		if err = json.Unmarshal(request, &req); err == nil {
+			result, err = h.detectVisual(ctx, req, start)
+		}
+	default:
+		err = fmt.Errorf("%w: %s", jobs.ErrUnknownJobType, jobType)
+	}
+
+	var apiErr *models.APIError
+	if errors.As(err, &apiErr) {
+		h.logger.WithContext(ctx).WithError(err).Error(apiErr.Message)
+		return nil, apiErr
+	}
+	return result, err
+}
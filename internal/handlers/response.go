@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/respond"
+)
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	respond.JSON(w, status, v)
+}
+
+// writeError writes err as a structured error response carrying the request ID, logging its cause
+func writeError(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, err error) {
+	respond.Error(w, r, logger, err)
+}
@@ -0,0 +1,229 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/metrics"
+)
+
+// Check statuses
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+	StatusPending = "pending"
+)
+
+// CheckFunc probes one dependency and returns an error when it is unhealthy
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the latest outcome of one check
+type CheckResult struct {
+	Status      string     `json:"status"`
+	LatencyMs   float64    `json:"latency_ms"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+	// running is set while a run of fn hasn't returned, which may outlast its timeout
+	running atomic.Bool
+}
+
+// Checker runs readiness checks at startup and periodically, so probes report the cached
+// results instead of running expensive checks on every request
+type Checker struct {
+	config config.HealthConfig
+	logger *logrus.Logger
+
+	checks []*check
+
+	mu      sync.RWMutex
+	results map[string]CheckResult
+
+	draining atomic.Bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewChecker creates a new readiness checker instance
+func NewChecker(cfg config.HealthConfig, logger *logrus.Logger) *Checker {
+	return &Checker{
+		config:  cfg,
+		logger:  logger,
+		results: make(map[string]CheckResult),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Register adds a named check; checks must be registered before Start
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, fn: fn})
+	c.results[name] = CheckResult{Status: StatusPending}
+}
+
+// Start runs every check once, then keeps re-running them every CheckInterval
+func (c *Checker) Start() {
+	c.runAll()
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.runAll()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic checks
+func (c *Checker) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// SetDraining makes the service report not ready from now on, so load balancers stop
+// routing to it before it shuts down
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether SetDraining was called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Ready reports whether every check passed on its latest run and the service is not
+// draining, along with the check results
+func (c *Checker) Ready() (bool, map[string]CheckResult) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ready := !c.Draining()
+	results := make(map[string]CheckResult, len(c.results))
+	for name, result := range c.results {
+		results[name] = result
+		if result.Status != StatusOK {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+// runAll runs every check concurrently and records their results, returning once each
+// has finished or timed out
+func (c *Checker) runAll() {
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk *check) {
+			defer wg.Done()
+			c.run(chk)
+		}(chk)
+	}
+	wg.Wait()
+}
+
+// run runs one check and records its result. A check that doesn't return within
+// CheckTimeout fails, and keeps failing without being started again until it returns.
+func (c *Checker) run(chk *check) {
+	start := time.Now()
+	if !chk.running.CompareAndSwap(false, true) {
+		c.record(chk.name, start, 0, errors.New("previous run still in progress"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.CheckTimeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		defer chk.running.Store(false)
+		errc <- chk.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.config.CheckTimeout)
+	}
+	c.record(chk.name, start, time.Since(start), err)
+}
+
+// record stores the outcome of one check run, keeping the last error across successes
+func (c *Checker) record(name string, at time.Time, latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := c.results[name]
+	previous := result.Status
+	result.LatencyMs = latency.Seconds() * 1000
+	result.CheckedAt = &at
+
+	if err != nil {
+		result.Status = StatusFailing
+		result.LastError = err.Error()
+		result.LastErrorAt = &at
+		metrics.ReadinessCheck.WithLabelValues(name).Set(0)
+	} else {
+		result.Status = StatusOK
+		metrics.ReadinessCheck.WithLabelValues(name).Set(1)
+	}
+	c.results[name] = result
+
+	if result.Status != previous {
+		entry := c.logger.WithFields(logrus.Fields{
+			"check":      name,
+			"latency_ms": result.LatencyMs,
+		})
+		if err != nil {
+			entry.WithError(err).Warn("Readiness check failing")
+		} else {
+			entry.Info("Readiness check passing")
+		}
+	}
+}
+
+// HeapCheck fails when the Go heap exceeds maxBytes; zero disables the limit
+func HeapCheck(maxBytes int64) CheckFunc {
+	return func(ctx context.Context) error {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		if maxBytes > 0 && int64(stats.HeapAlloc) > maxBytes {
+			return fmt.Errorf("heap in use %d bytes exceeds limit of %d bytes", stats.HeapAlloc, maxBytes)
+		}
+		return nil
+	}
+}
+
+// SaturationCheck fails when saturation reports a fraction of capacity in use of at least max
+func SaturationCheck(saturation func() float64, max float64) CheckFunc {
+	return func(ctx context.Context) error {
+		if s := saturation(); max > 0 && s >= max {
+			return fmt.Errorf("saturation %.2f reached limit of %.2f", s, max)
+		}
+		return nil
+	}
+}
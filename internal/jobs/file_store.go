@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errCorruptJobFile is returned for a job file that was read but doesn't hold a job
+var errCorruptJobFile = errors.New("corrupt job file")
+
+// FileStore persists each job as a JSON file in a directory so jobs survive restarts
+type FileStore struct {
+	dir    string
+	mu     sync.RWMutex
+	logger *logrus.Logger
+}
+
+// NewFileStore creates a file-backed job store, creating dir if needed
+func NewFileStore(dir string, logger *logrus.Logger) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	return &FileStore{dir: dir, logger: logger}, nil
+}
+
+// Save creates or replaces a job. The file is written to a temporary name and renamed
+// into place so readers never observe a partially written job.
+func (s *FileStore) Save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.path(job.ID)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".job-*")
+	if err != nil {
+		return fmt.Errorf("failed to create job file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write job file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store job file: %w", err)
+	}
+	return nil
+}
+
+// Get returns the job with the given ID
+func (s *FileStore) Get(ctx context.Context, id string) (*Job, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return readJobFile(path)
+}
+
+// Delete removes the job with the given ID
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete job file: %w", err)
+	}
+	return nil
+}
+
+// List returns all stored jobs. A file that can't be read is logged and skipped, and one
+// that can't be decoded is also renamed aside with a .corrupt suffix, so one bad file
+// doesn't keep the others from resuming.
+func (s *FileStore) List(ctx context.Context) ([]*Job, error) {
+	// Held exclusively since corrupt files may be renamed
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job files: %w", err)
+	}
+
+	jobs := make([]*Job, 0, len(paths))
+	for _, path := range paths {
+		job, err := readJobFile(path)
+		if errors.Is(err, ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			s.skipJobFile(path, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// skipJobFile logs a job file List can't load, renaming it aside when its contents are corrupt
+func (s *FileStore) skipJobFile(path string, err error) {
+	entry := s.logger.WithError(err).WithField("file", filepath.Base(path))
+
+	if !errors.Is(err, errCorruptJobFile) {
+		entry.Error("Skipping unreadable job file")
+		return
+	}
+
+	aside := path + ".corrupt"
+	if renameErr := os.Rename(path, aside); renameErr != nil {
+		entry.WithField("rename_error", renameErr.Error()).Error("Skipping corrupt job file")
+		return
+	}
+	entry.WithField("moved_to", filepath.Base(aside)).Error("Moved corrupt job file aside")
+}
+
+// path returns the file path for a job ID, rejecting IDs that could escape the directory
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid job ID: %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// readJobFile loads a job from a JSON file
+func readJobFile(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errCorruptJobFile, filepath.Base(path), err)
+	}
+	return &job, nil
+}
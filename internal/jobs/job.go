@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"face-recognition-api/internal/models"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+// Job statuses
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Terminal reports whether the status is final
+func (s Status) Terminal() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job types
+const (
+	TypeDetect   = "detect"
+	TypeValidate = "validate"
+	TypeVisual   = "visual"
+)
+
+// Job is a unit of asynchronous work along with its current state and outcome
+type Job struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	Status      Status           `json:"status"`
+	Progress    float64          `json:"progress"`
+	Request     json.RawMessage  `json:"request"`
+	CallbackURL string           `json:"callback_url,omitempty"`
+	RequestID   string           `json:"request_id,omitempty"`
+	Owner       string           `json:"owner,omitempty"`
+	Result      json.RawMessage  `json:"result,omitempty"`
+	Error       *models.APIError `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+// Processor validates and runs the work behind a job
+type Processor interface {
+	// ValidateJob checks a job request before it is queued
+	ValidateJob(jobType string, request json.RawMessage) error
+	// ProcessJob runs the job and returns its JSON-serializable result
+	ProcessJob(ctx context.Context, jobType string, request json.RawMessage) (interface{}, error)
+}
+
+type progressKey struct{}
+
+// ReportProgress records the fraction of work done for the job running with ctx.
+// It is a no-op when ctx does not belong to a job.
+func ReportProgress(ctx context.Context, fraction float64) {
+	if report, ok := ctx.Value(progressKey{}).(func(float64)); ok {
+		report(fraction)
+	}
+}
+
+// withProgress attaches a progress reporter to ctx
+func withProgress(ctx context.Context, report func(float64)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
@@ -0,0 +1,547 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/auth"
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/requestid"
+	"face-recognition-api/internal/services"
+)
+
+// Manager errors
+var (
+	ErrQueueFull      = errors.New("job queue is full")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrShuttingDown   = errors.New("job manager is shutting down")
+)
+
+// Manager queues jobs and runs them on a fixed pool of in-process workers
+type Manager struct {
+	config    config.JobsConfig
+	store     Store
+	processor Processor
+	webhooks  *services.WebhookDispatcher
+	logger    *logrus.Logger
+
+	queue chan string
+
+	// mu serializes read-modify-write cycles on stored jobs
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+
+	// draining rejects new jobs; pending counts queued and running jobs. Both are guarded by mu.
+	draining bool
+	pending  int
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a new job manager instance
+func NewManager(
+	cfg config.JobsConfig,
+	store Store,
+	processor Processor,
+	webhooks *services.WebhookDispatcher,
+	logger *logrus.Logger,
+) *Manager {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Manager{
+		config:    cfg,
+		store:     store,
+		processor: processor,
+		webhooks:  webhooks,
+		logger:    logger,
+		queue:     make(chan string, max(cfg.QueueSize, 1)),
+		cancels:   make(map[string]context.CancelFunc),
+		ctx:       ctx,
+		stop:      stop,
+	}
+}
+
+// Stats reports the number of queued and running jobs
+func (m *Manager) Stats() (queued, running int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued = len(m.queue)
+	return queued, max(m.pending-queued, 0)
+}
+
+// Start re-queues jobs left unfinished by a previous run and starts the workers
+func (m *Manager) Start() error {
+	if err := m.recover(); err != nil {
+		return err
+	}
+
+	workers := max(m.config.Workers, 1)
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+
+	m.wg.Add(1)
+	go m.cleanup()
+
+	m.logger.WithFields(logrus.Fields{
+		"workers":    workers,
+		"queue_size": cap(m.queue),
+	}).Info("Job manager started")
+
+	return nil
+}
+
+// Stop cancels running jobs and waits for the workers to exit. Interrupted jobs are put
+// back in the queued state so a persistent store can resume them after a restart.
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// Drain stops accepting jobs and waits for queued and running jobs to finish until ctx is
+// done. Jobs still unfinished then are interrupted, left queued in the store and returned by
+// ID; a persistent store resumes them on the next start.
+func (m *Manager) Drain(ctx context.Context) (running, queued []string) {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !m.idle() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return m.abandon()
+		}
+	}
+
+	m.Stop()
+	return nil, nil
+}
+
+// idle reports whether no job is queued or running
+func (m *Manager) idle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pending == 0
+}
+
+// abandon interrupts running jobs, stops the workers and returns the IDs of the jobs that
+// did not finish
+func (m *Manager) abandon() (running, queued []string) {
+	m.mu.Lock()
+	for id := range m.cancels {
+		running = append(running, id)
+	}
+	m.mu.Unlock()
+
+	m.Stop()
+
+	// Workers have exited, so whatever is left in the queue never started
+	for {
+		select {
+		case id := <-m.queue:
+			queued = append(queued, id)
+		default:
+			return running, queued
+		}
+	}
+}
+
+// Submit validates and enqueues a new job. When callbackURL is set, the finished job is
+// POSTed there as a signed "job.<status>" callback.
+func (m *Manager) Submit(ctx context.Context, jobType string, request json.RawMessage, callbackURL string) (*Job, error) {
+	switch jobType {
+	case TypeDetect, TypeValidate, TypeVisual:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+
+	if callbackURL != "" {
+		if err := m.webhooks.ValidateCallbackURL(callbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.processor.ValidateJob(jobType, request); err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:          id,
+		Type:        jobType,
+		Status:      StatusQueued,
+		Request:     request,
+		CallbackURL: callbackURL,
+		RequestID:   requestid.FromContext(ctx),
+		Owner:       owner(ctx),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	m.mu.Lock()
+	if m.draining {
+		m.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	m.pending++
+	m.mu.Unlock()
+
+	if err := m.store.Save(ctx, job); err != nil {
+		m.done()
+		return nil, err
+	}
+
+	select {
+	case m.queue <- id:
+	default:
+		m.store.Delete(ctx, id)
+		m.done()
+		return nil, ErrQueueFull
+	}
+
+	m.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":   id,
+		"job_type": jobType,
+	}).Info("Job queued")
+
+	return job, nil
+}
+
+// Get returns the current state of a job
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	return m.store.Get(ctx, id)
+}
+
+// Cancel cancels a queued or running job
+func (m *Manager) Cancel(ctx context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.Terminal() {
+		return job, ErrJobFinished
+	}
+
+	now := time.Now().UTC()
+	job.Status = StatusCanceled
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	if err := m.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// A running job notices cancellation through its context; a queued one is skipped
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+
+	m.logger.WithContext(ctx).WithField("job_id", id).Info("Job canceled")
+	recordFinished(job)
+	m.notify(job)
+
+	return job, nil
+}
+
+// worker runs queued jobs until the manager stops
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(id)
+		}
+	}
+}
+
+// run executes one job and records its outcome
+func (m *Manager) run(id string) {
+	defer m.done()
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Timeout)
+	defer cancel()
+
+	job, err := m.update(id, func(job *Job) bool {
+		if job.Status != StatusQueued {
+			return false
+		}
+		now := time.Now().UTC()
+		job.Status = StatusRunning
+		job.StartedAt = &now
+		m.cancels[id] = cancel
+		return true
+	})
+	if err != nil {
+		m.logger.WithError(err).WithField("job_id", id).Error("Failed to start job")
+		return
+	}
+	if job.Status != StatusRunning {
+		return
+	}
+
+	// Processing logs and outbound calls carry the ID of the request that submitted the job
+	ctx = requestid.NewContext(ctx, job.RequestID)
+
+	report := func(fraction float64) {
+		m.update(id, func(job *Job) bool {
+			if job.Status != StatusRunning || fraction <= job.Progress {
+				return false
+			}
+			job.Progress = fraction
+			return true
+		})
+	}
+
+	start := time.Now()
+	metrics.JobsRunning.Inc()
+	result, procErr := m.processor.ProcessJob(withProgress(ctx, report), job.Type, job.Request)
+	metrics.JobsRunning.Dec()
+
+	var resultJSON json.RawMessage
+	if procErr == nil {
+		resultJSON, procErr = json.Marshal(result)
+	}
+
+	// Cancel records and notifies jobs it cancels, so only a transition made here counts
+	transitioned := false
+	job, err = m.update(id, func(job *Job) bool {
+		delete(m.cancels, id)
+
+		// Canceled jobs keep the state set by Cancel
+		if job.Status != StatusRunning {
+			return false
+		}
+		transitioned = true
+
+		now := time.Now().UTC()
+		switch {
+		case m.ctx.Err() != nil:
+			// Interrupted by shutdown; leave it for a restart to pick up again
+			job.Status = StatusQueued
+			job.Progress = 0
+			job.StartedAt = nil
+			return true
+		case procErr == nil:
+			job.Status = StatusSucceeded
+			job.Progress = 1
+			job.Result = resultJSON
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			job.Status = StatusFailed
+			job.Error = models.ErrJobTimeout
+		default:
+			job.Status = StatusFailed
+			job.Error = toAPIError(procErr)
+		}
+		job.CompletedAt = &now
+		return true
+	})
+	if err != nil {
+		m.logger.WithError(err).WithField("job_id", id).Error("Failed to record job result")
+		return
+	}
+
+	if transitioned && job.Status.Terminal() {
+		recordFinished(job)
+		m.notify(job)
+	}
+
+	entry := m.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":          id,
+		"job_type":        job.Type,
+		"status":          job.Status,
+		"processing_time": time.Since(start).Milliseconds(),
+	})
+	if procErr != nil {
+		entry = entry.WithError(procErr)
+	}
+	entry.Info("Job finished")
+}
+
+// done marks one queued or running job as no longer pending
+func (m *Manager) done() {
+	m.mu.Lock()
+	m.pending--
+	m.mu.Unlock()
+}
+
+// notify queues the finished job for delivery to its callback URL
+func (m *Manager) notify(job *Job) {
+	if job.CallbackURL == "" {
+		return
+	}
+
+	ctx := requestid.NewContext(context.Background(), job.RequestID)
+	if err := m.webhooks.Deliver(ctx, job.CallbackURL, "job."+string(job.Status), job, job.Error); err != nil {
+		m.logger.WithContext(ctx).WithError(err).WithField("job_id", job.ID).Warn("Failed to queue job callback")
+	}
+}
+
+// update applies fn to the stored job under the manager lock and saves it when fn reports a change
+func (m *Manager) update(id string, fn func(job *Job) bool) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Use a background context so state changes are recorded even while shutting down
+	ctx := context.Background()
+
+	job, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fn(job) {
+		return job, nil
+	}
+
+	job.UpdatedAt = time.Now().UTC()
+	if err := m.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// recover re-queues jobs that were queued or running when the process last stopped
+func (m *Manager) recover() error {
+	ctx := context.Background()
+
+	jobs, err := m.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list stored jobs: %w", err)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	recovered := 0
+	for _, job := range jobs {
+		if job.Status.Terminal() {
+			continue
+		}
+
+		job.Status = StatusQueued
+		job.Progress = 0
+		job.StartedAt = nil
+		job.UpdatedAt = time.Now().UTC()
+
+		select {
+		case m.queue <- job.ID:
+			m.pending++
+			recovered++
+		default:
+			now := time.Now().UTC()
+			job.Status = StatusFailed
+			job.CompletedAt = &now
+			job.Error = models.ErrJobQueueFull
+		}
+
+		if err := m.store.Save(ctx, job); err != nil {
+			return fmt.Errorf("failed to recover job %s: %w", job.ID, err)
+		}
+	}
+
+	if recovered > 0 {
+		m.logger.WithField("jobs", recovered).Info("Recovered unfinished jobs")
+	}
+	return nil
+}
+
+// cleanup periodically deletes finished jobs older than the retention period
+func (m *Manager) cleanup() {
+	defer m.wg.Done()
+
+	interval := min(m.config.Retention/10, time.Minute)
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		jobs, err := m.store.List(m.ctx)
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to list jobs for cleanup")
+			continue
+		}
+
+		cutoff := time.Now().Add(-m.config.Retention)
+		for _, job := range jobs {
+			if job.Status.Terminal() && job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
+				if err := m.store.Delete(m.ctx, job.ID); err != nil {
+					m.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to delete expired job")
+				}
+			}
+		}
+	}
+}
+
+// owner returns the principal of the authenticated caller in ctx, or "" when auth is
+// disabled
+func owner(ctx context.Context) string {
+	if identity := auth.FromContext(ctx); identity != nil {
+		return identity.Principal()
+	}
+	return ""
+}
+
+// recordFinished counts a job reaching a terminal state and the error it failed with
+func recordFinished(job *Job) {
+	metrics.JobsFinished.WithLabelValues(job.Type, string(job.Status)).Inc()
+	if job.Error != nil {
+		metrics.Errors.WithLabelValues(job.Error.Code).Inc()
+	}
+}
+
+// toAPIError converts a processing error into the structured error stored on the job
+func toAPIError(err error) *models.APIError {
+	var apiErr *models.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return models.ErrInternal
+}
+
+// newJobID returns a random 128-bit hex job identifier
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrJobNotFound is returned when a job does not exist in the store
+var ErrJobNotFound = errors.New("job not found")
+
+// Store persists jobs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save creates or replaces a job
+	Save(ctx context.Context, job *Job) error
+	// Get returns a copy of the job with the given ID
+	Get(ctx context.Context, id string) (*Job, error)
+	// Delete removes the job with the given ID
+	Delete(ctx context.Context, id string) error
+	// List returns copies of all stored jobs
+	List(ctx context.Context) ([]*Job, error)
+}
+
+// MemoryStore keeps jobs in process memory; they are lost on restart
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewMemoryStore creates a new in-memory job store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Save creates or replaces a job
+func (s *MemoryStore) Save(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// Get returns a copy of the job with the given ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	copied := *job
+	return &copied, nil
+}
+
+// Delete removes the job with the given ID
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return nil
+}
+
+// List returns copies of all stored jobs
+func (s *MemoryStore) List(ctx context.Context) ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		copied := *job
+		jobs = append(jobs, &copied)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace prefixes every metric exported by the service
+const namespace = "face_recognition"
+
+// Detection worker pool metrics
+var (
+	DetectionQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "detection_queue_depth",
+		Help:      "Number of detections waiting for a free worker.",
+	})
+
+	DetectionWorkersBusy = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "detection_workers_busy",
+		Help:      "Number of detection workers currently running.",
+	})
+
+	DetectionQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "detection_queue_wait_seconds",
+		Help:      "Time detections spent waiting for a free worker.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	DetectionRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "detection_rejected_total",
+		Help:      "Detections rejected before running, by reason (queue_full, queue_timeout, canceled).",
+	}, []string{"reason"})
+)
+
+// HTTP metrics, labeled by route template rather than raw path to keep cardinality bounded
+var (
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Total HTTP request latency by endpoint, method and status code.",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint", "method", "status"})
+
+	RequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served by endpoint.",
+	}, []string{"endpoint"})
+
+	Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors returned to clients by API error code.",
+	}, []string{"code"})
+)
+
+// Image pipeline metrics
+var (
+	DownloadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_download_duration_seconds",
+		Help:      "Image download latency, from request start to body fully read, by outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"outcome"})
+
+	DecodeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_decode_duration_seconds",
+		Help:      "Image decode latency by image format.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"format"})
+
+	DetectionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "detection_duration_seconds",
+		Help:      "Face detection latency by detection backend, excluding time queued for a worker.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"backend"})
+
+	FacesPerImage = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "faces_per_image",
+		Help:      "Number of faces detected per image by detection backend.",
+		Buckets:   []float64{0, 1, 2, 3, 5, 10, 20, 50},
+	}, []string{"backend"})
+
+	ImageSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_size_bytes",
+		Help:      "Size of processed images in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(16*1024, 2, 10),
+	})
+
+	ImageDimension = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_dimension_pixels",
+		Help:      "Width and height of processed images in pixels.",
+		Buckets:   []float64{128, 256, 512, 768, 1024, 1536, 2048, 3072, 4096},
+	}, []string{"dimension"})
+
+	SelfieValidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "selfie_validations_total",
+		Help:      "Selfie validations by outcome (valid, invalid).",
+	}, []string{"outcome"})
+
+	SelfieValidationIssues = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "selfie_validation_issues_total",
+		Help:      "Selfie validation issues by issue code.",
+	}, []string{"issue"})
+)
+
+// Shadow detection metrics; deltas are the shadow result minus the primary result
+var (
+	ShadowComparisons = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_comparisons_total",
+		Help:      "Sampled detections repeated with the shadow detector, by outcome (agree, disagree, error, dropped).",
+	}, []string{"outcome"})
+
+	ShadowFaceCountDelta = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shadow_face_count_delta",
+		Help:      "Faces found by the shadow detector minus faces found by the primary detector.",
+		Buckets:   prometheus.LinearBuckets(-5, 1, 11),
+	})
+
+	ShadowUnmatchedFaces = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_unmatched_faces_total",
+		Help:      "Faces found by only one of the detectors, by the side that found them (primary, shadow).",
+	}, []string{"side"})
+
+	ShadowConfidenceDelta = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shadow_confidence_delta",
+		Help:      "Shadow minus primary confidence of matched faces.",
+		Buckets:   []float64{-20, -10, -5, -2, -1, -0.5, -0.1, 0, 0.1, 0.5, 1, 2, 5, 10, 20},
+	})
+
+	ShadowMatchIoU = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shadow_match_iou",
+		Help:      "Intersection over union of matched primary and shadow face boxes.",
+		Buckets:   []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+	})
+
+	ShadowDetectionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shadow_detection_duration_seconds",
+		Help:      "Face detection latency of the shadow detector.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+)
+
+// Job metrics
+var (
+	JobsRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_running",
+		Help:      "Asynchronous jobs currently being processed.",
+	})
+
+	JobsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_finished_total",
+		Help:      "Asynchronous jobs reaching a terminal state, by job type and status.",
+	}, []string{"type", "status"})
+)
+
+// ReadinessCheck reports whether each readiness check passed on its latest run
+var ReadinessCheck = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "readiness_check_up",
+	Help:      "Whether a readiness check passed on its latest run (1) or failed (0).",
+}, []string{"check"})
+
+// ConfigReloads counts configuration reload attempts by outcome
+var ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "config_reloads_total",
+	Help:      "Configuration reloads by outcome (success, error).",
+}, []string{"outcome"})
+
+// Authentication metrics; client IDs are configured API key IDs or token issuers, so
+// cardinality stays bounded
+var (
+	AuthRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_requests_total",
+		Help:      "Authenticated requests by client ID and scope.",
+	}, []string{"client_id", "scope"})
+
+	AuthFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Rejected requests by reason (missing, invalid, expired, forbidden).",
+	}, []string{"reason"})
+
+	JWKSRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_jwks_refreshes_total",
+		Help:      "JWT signing key set loads by outcome (success, error).",
+	}, []string{"outcome"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the rate limiter, by endpoint class and reason (rate, daily, monthly).",
+	}, []string{"class", "reason"})
+)
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"face-recognition-api/internal/auth"
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/respond"
+)
+
+// APIKeyHeader carries the caller's API key
+const APIKeyHeader = "X-API-Key"
+
+// credentials are the accepted API keys and token issuers; either may be nil
+type credentials struct {
+	keys   *auth.KeyStore
+	tokens *auth.TokenVerifier
+}
+
+// Auth authenticates requests with API keys or JWT bearer tokens and enforces per-route scopes
+type Auth struct {
+	enabled     bool
+	credentials atomic.Pointer[credentials]
+	logger      *logrus.Logger
+}
+
+// NewAuth creates a new auth middleware factory; authentication is disabled when neither
+// a key store nor a token verifier is given
+func NewAuth(keys *auth.KeyStore, tokens *auth.TokenVerifier, logger *logrus.Logger) *Auth {
+	a := &Auth{
+		enabled: keys != nil || tokens != nil,
+		logger:  logger,
+	}
+	a.SetCredentials(keys, tokens)
+	return a
+}
+
+// SetCredentials replaces the accepted API keys and token issuers. It can't enable or
+// disable authentication, which is decided when the middleware is created.
+func (a *Auth) SetCredentials(keys *auth.KeyStore, tokens *auth.TokenVerifier) {
+	a.credentials.Store(&credentials{keys: keys, tokens: tokens})
+}
+
+// Credentials returns the accepted API keys and token issuers; either may be nil
+func (a *Auth) Credentials() (*auth.KeyStore, *auth.TokenVerifier) {
+	creds := a.credentials.Load()
+	return creds.keys, creds.tokens
+}
+
+// Authenticate returns middleware that resolves the caller identity from a bearer token or
+// an API key and stores it in the request context. Requests without credentials continue
+// anonymously and are rejected by Require on protected routes; invalid credentials are
+// rejected on every route.
+func (a *Auth) Authenticate() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !a.enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := a.identify(r)
+			if errors.Is(err, auth.ErrMissingCredentials) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				a.reject(w, r, err)
+				return
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", identity.ID))
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), identity)))
+		})
+	}
+}
+
+// identify authenticates the credentials presented with r. Bearer tokens take precedence
+// over API keys when token authentication is configured.
+func (a *Auth) identify(r *http.Request) (*auth.Identity, error) {
+	creds := a.credentials.Load()
+	if creds.tokens != nil {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return creds.tokens.Verify(r.Context(), strings.TrimSpace(token), time.Now())
+		}
+	}
+	if creds.keys != nil {
+		return creds.keys.Authenticate(r.Header.Get(APIKeyHeader), time.Now())
+	}
+	return nil, auth.ErrMissingCredentials
+}
+
+// Require returns middleware that rejects requests whose identity lacks scope. An empty
+// scope only requires authentication; handlers then check scopes themselves.
+// It relies on Authenticate running earlier in the chain.
+func (a *Auth) Require(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !a.enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := auth.FromContext(r.Context())
+			if identity == nil {
+				a.reject(w, r, auth.ErrMissingCredentials)
+				return
+			}
+
+			if scope != "" && !identity.HasScope(scope) {
+				metrics.AuthFailures.WithLabelValues("forbidden").Inc()
+				respond.Error(w, r, a.logger, models.ErrForbidden.WithDetail("requires scope %s", scope))
+				return
+			}
+
+			label := string(scope)
+			if label == "" {
+				label = "any"
+			}
+			metrics.AuthRequests.WithLabelValues(clientLabel(identity), label).Inc()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientLabel bounds the client_id metric label: API key IDs are configured, but token
+// subjects are not, so tokens are counted per issuer
+func clientLabel(identity *auth.Identity) string {
+	if identity.Method == "jwt" {
+		return "jwt:" + identity.Metadata["issuer"]
+	}
+	return identity.ID
+}
+
+// reject writes the 401 response for a failed authentication
+func (a *Auth) reject(w http.ResponseWriter, r *http.Request, err error) {
+	reason, detail := "invalid", "invalid API key"
+	switch {
+	case errors.Is(err, auth.ErrMissingCredentials):
+		reason, detail = "missing", "credentials required"
+	case errors.Is(err, auth.ErrKeyExpired):
+		reason, detail = "expired", "API key expired"
+	case errors.Is(err, auth.ErrTokenExpired):
+		reason, detail = "expired", "bearer token expired"
+	case errors.Is(err, auth.ErrInvalidToken):
+		detail = "invalid bearer token"
+	}
+	metrics.AuthFailures.WithLabelValues(reason).Inc()
+
+	a.logger.WithContext(r.Context()).WithError(err).Warn("Authentication failed")
+
+	creds := a.credentials.Load()
+	if creds.keys != nil {
+		w.Header().Add("WWW-Authenticate", `ApiKey header="`+APIKeyHeader+`"`)
+	}
+	if creds.tokens != nil {
+		challenge := `Bearer realm="face-recognition-api"`
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenExpired) {
+			challenge += `, error="invalid_token"`
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+	respond.Error(w, r, a.logger, models.ErrUnauthorized.WithDetail("%s", detail))
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// InFlight counts requests being served, so shutdown can report the ones it abandons
+type InFlight struct {
+	count atomic.Int64
+}
+
+// Middleware returns middleware that counts requests while they are served
+func (f *InFlight) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f.count.Add(1)
+			defer f.count.Add(-1)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Count returns the number of requests being served
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
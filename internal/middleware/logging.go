@@ -25,6 +25,18 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// Flush implements http.Flusher so streaming handlers work behind the middleware
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LoggingMiddleware logs HTTP requests with structured logging
 func LoggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -43,7 +55,7 @@ func LoggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 			// Log the request
 			duration := time.Since(start)
 			
-			logger.WithFields(logrus.Fields{
+			logger.WithContext(r.Context()).WithFields(logrus.Fields{
 				"method":      r.Method,
 				"path":        r.URL.Path,
 				"query":       r.URL.RawQuery,
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"face-recognition-api/internal/metrics"
+)
+
+// MetricsMiddleware records request latency and in-flight requests per endpoint. Endpoints
+// are labeled with the matched route template, e.g. /api/v1/jobs/{id}, so label cardinality
+// stays bounded no matter which paths clients request.
+func MetricsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			endpoint := routeTemplate(r)
+			inFlight := metrics.RequestsInFlight.WithLabelValues(endpoint)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			metrics.RequestDuration.
+				WithLabelValues(endpoint, r.Method, strconv.Itoa(wrapped.statusCode)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route that matched r
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/auth"
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/ratelimit"
+	"face-recognition-api/internal/respond"
+)
+
+// RateLimit enforces per-client rate limits and quotas
+type RateLimit struct {
+	limiter    *ratelimit.Limiter
+	trustProxy bool
+	logger     *logrus.Logger
+}
+
+// NewRateLimit creates a new rate limit middleware factory; a nil limiter disables limiting.
+// With trustProxy, anonymous clients are identified by the address a single reverse proxy
+// appends to X-Forwarded-For instead of the connection's remote address.
+func NewRateLimit(limiter *ratelimit.Limiter, trustProxy bool, logger *logrus.Logger) *RateLimit {
+	return &RateLimit{
+		limiter:    limiter,
+		trustProxy: trustProxy,
+		logger:     logger,
+	}
+}
+
+// Limit returns middleware applying the limits of class. Authenticated clients are limited
+// per identity, anonymous ones per IP address. Requests are let through when the limiter
+// backend fails.
+func (l *RateLimit) Limit(class ratelimit.Class) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			result, err := l.limiter.Allow(r.Context(), l.clientKey(r), class, now)
+			if err != nil {
+				l.logger.WithContext(r.Context()).WithError(err).Warn("Rate limiter unavailable, allowing request")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if result.Limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+				w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+				w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset.Sub(now))))
+			}
+
+			if !result.Allowed {
+				metrics.RateLimited.WithLabelValues(string(class), string(result.Reason)).Inc()
+				retryAfter := max(ceilSeconds(result.RetryAfter), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+				apiErr := models.ErrRateLimited.WithDetail("%s rate limit exceeded, retry in %ds", class, retryAfter)
+				if result.Reason != ratelimit.ReasonRate {
+					apiErr = models.ErrQuotaExceeded.WithDetail("%s quota of %d requests exhausted until %s",
+						result.Reason, result.Limit, result.Reset.UTC().Format(time.RFC3339))
+				}
+				respond.Error(w, r, l.logger, apiErr)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the client whose limits apply to r
+func (l *RateLimit) clientKey(r *http.Request) string {
+	if identity := auth.FromContext(r.Context()); identity != nil {
+		return identity.Principal()
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP returns the address of an anonymous client
+func (l *RateLimit) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		forwarded := r.Header.Get("X-Forwarded-For")
+		if i := strings.LastIndex(forwarded, ","); i >= 0 {
+			forwarded = forwarded[i+1:]
+		}
+		if ip := strings.TrimSpace(forwarded); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -1,11 +1,13 @@
 package middleware
 
 import (
-	"encoding/json"
 	"net/http"
 	"runtime/debug"
 
 	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/respond"
 )
 
 // RecoveryMiddleware recovers from panics and logs them
@@ -15,7 +17,7 @@ func RecoveryMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 			defer func() {
 				if err := recover(); err != nil {
 					// Log the panic with stack trace
-					logger.WithFields(logrus.Fields{
+					logger.WithContext(r.Context()).WithFields(logrus.Fields{
 						"error":      err,
 						"method":     r.Method,
 						"path":       r.URL.Path,
@@ -25,15 +27,7 @@ func RecoveryMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 					}).Error("Panic recovered")
 
 					// Return 500 error response
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-
-					errorResponse := map[string]interface{}{
-						"error": "Internal server error",
-						"code":  "INTERNAL_ERROR",
-					}
-
-					json.NewEncoder(w).Encode(errorResponse)
+					respond.Error(w, r, logger, models.ErrInternal)
 				}
 			}()
 
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"face-recognition-api/internal/requestid"
+)
+
+// RequestIDMiddleware accepts a well-formed X-Request-ID from the client or generates one,
+// stores it in the request context and echoes it in the response headers
+func RequestIDMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !requestid.Valid(id) {
+				id = requestid.New()
+			}
+
+			w.Header().Set(requestid.Header, id)
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", id))
+
+			next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// TracingMiddleware starts a server span for every request, continuing any trace passed in
+// a W3C traceparent header. Spans are named after the matched route template.
+func TracingMiddleware() func(http.Handler) http.Handler {
+	return otelhttp.NewMiddleware("http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeTemplate(r)
+		}),
+	)
+}
@@ -1,20 +1,71 @@
 package models
 
+import "encoding/json"
+
+// DetectorSelection picks the detection backend for a request, either by backend name,
+// optionally with one of its cascades, or by a configured profile; when all are empty the
+// default backend is used
+type DetectorSelection struct {
+	Detector string `json:"detector,omitempty"`
+	Cascade  string `json:"cascade,omitempty"`
+	Profile  string `json:"profile,omitempty"`
+}
+
 // FaceDetectionRequest represents the request for face detection endpoint
 type FaceDetectionRequest struct {
-	ImageURL string `json:"image_url" binding:"required,url"`
+	ImageURL    string `json:"image_url" binding:"required,url"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	DetectorSelection
 }
 
 // SelfieValidationRequest represents the request for selfie validation endpoint
 type SelfieValidationRequest struct {
-	ImageURL string `json:"image_url" binding:"required,url"`
-	MinFaces int    `json:"min_faces" default:"1"`
-	MaxFaces int    `json:"max_faces" default:"1"`
+	ImageURL    string `json:"image_url" binding:"required,url"`
+	MinFaces    int    `json:"min_faces" default:"1"`
+	MaxFaces    int    `json:"max_faces" default:"1"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	DetectorSelection
 }
 
+// Output formats supported by the visual detection endpoint
+const (
+	OutputFormatImage = "image"
+	OutputFormatSVG   = "svg"
+)
+
 // VisualDetectionRequest represents the request for visual detection endpoint
 type VisualDetectionRequest struct {
-	ImageURL    string `json:"image_url" binding:"required,url"`
-	CircleColor string `json:"circle_color" default:"red"`
-	LineWidth   int    `json:"line_width" default:"3"`
-}
\ No newline at end of file
+	ImageURL     string `json:"image_url" binding:"required,url"`
+	CircleColor  string `json:"circle_color" default:"red"`
+	LineWidth    int    `json:"line_width" default:"3"`
+	OutputFormat string `json:"output_format" default:"image"`
+	CallbackURL  string `json:"callback_url,omitempty"`
+	DetectorSelection
+}
+
+// ImageReference identifies one image of a batch, either by URL or by inline base64 data,
+// and optionally the backend to detect it with
+type ImageReference struct {
+	ImageURL    string `json:"image_url,omitempty"`
+	ImageBase64 string `json:"image_base64,omitempty"`
+	DetectorSelection
+
+	// Data holds raw bytes of an image uploaded as a multipart part
+	Data []byte `json:"-"`
+}
+
+// BatchDetectionRequest represents the request for batch face detection endpoint. Its
+// detector selection applies to images that don't select one themselves.
+type BatchDetectionRequest struct {
+	Images      []ImageReference `json:"images"`
+	CallbackURL string           `json:"callback_url,omitempty"`
+	DetectorSelection
+}
+
+// JobRequest represents the request for creating an asynchronous job. Request holds the same
+// body the matching synchronous endpoint accepts.
+type JobRequest struct {
+	Type        string          `json:"type"`
+	Request     json.RawMessage `json:"request"`
+	CallbackURL string          `json:"callback_url,omitempty"`
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 // Face represents a detected face with coordinates and confidence
 type Face struct {
 	X          int     `json:"x"`
@@ -7,6 +12,16 @@ type Face struct {
 	Width      int     `json:"width"`
 	Height     int     `json:"height"`
 	Confidence float32 `json:"confidence"`
+	// Landmarks are the facial points located inside the face, when requested
+	Landmarks []Landmark `json:"landmarks,omitempty"`
+}
+
+// Landmark is a named facial point in image coordinates. Left and right are as seen in
+// the image.
+type Landmark struct {
+	Name string `json:"name"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
 }
 
 // ImageMetadata contains metadata about the processed image
@@ -18,49 +33,199 @@ type ImageMetadata struct {
 	URL       string `json:"url"`
 }
 
+// DetectorInfo identifies the detection backend that produced a result, and the profile
+// that selected it if any
+type DetectorInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Profile string `json:"profile,omitempty"`
+}
+
 // FaceDetectionResponse represents the response for face detection endpoint
 type FaceDetectionResponse struct {
 	Faces            []Face        `json:"faces"`
 	Count            int           `json:"count"`
 	ImageMetadata    ImageMetadata `json:"image_metadata"`
+	Detector         DetectorInfo  `json:"detector"`
 	ProcessingTimeMs float64       `json:"processing_time_ms"`
 }
 
 // SelfieValidationResponse represents the response for selfie validation endpoint
 type SelfieValidationResponse struct {
-	IsValid    bool     `json:"is_valid"`
-	Issues     []string `json:"issues,omitempty"`
-	Confidence float32  `json:"confidence"`
-	FaceCount  int      `json:"face_count"`
+	IsValid    bool         `json:"is_valid"`
+	Issues     []string     `json:"issues,omitempty"`
+	IssueCodes []string     `json:"issue_codes,omitempty"`
+	Confidence float32      `json:"confidence"`
+	FaceCount  int          `json:"face_count"`
+	Detector   DetectorInfo `json:"detector"`
 }
 
 // VisualDetectionResponse represents the response for visual detection endpoint
 type VisualDetectionResponse struct {
-	ImageBase64      string        `json:"image_base64"`
+	ImageBase64      string        `json:"image_base64,omitempty"`
+	SVGOverlay       string        `json:"svg_overlay,omitempty"`
 	Faces            []Face        `json:"faces"`
 	Count            int           `json:"count"`
 	ImageMetadata    ImageMetadata `json:"image_metadata"`
+	Detector         DetectorInfo  `json:"detector"`
 	ProcessingTimeMs float64       `json:"processing_time_ms"`
 }
 
-// APIError represents a structured API error
+// BatchItemResult holds the outcome of one image of a batch, in request order
+type BatchItemResult struct {
+	Index  int                    `json:"index"`
+	Result *FaceDetectionResponse `json:"result,omitempty"`
+	Error  *APIError              `json:"error,omitempty"`
+}
+
+// BatchDetectionResponse represents the response for batch face detection endpoint
+type BatchDetectionResponse struct {
+	Results          []BatchItemResult `json:"results"`
+	Count            int               `json:"count"`
+	Succeeded        int               `json:"succeeded"`
+	Failed           int               `json:"failed"`
+	ProcessingTimeMs float64           `json:"processing_time_ms"`
+}
+
+// WebhookEvent is the payload POSTed to a request's callback URL when processing finishes
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     *APIError   `json:"error,omitempty"`
+}
+
+// APIError represents a structured API error. The predefined errors below are templates;
+// WithCause and WithDetail return copies describing one occurrence.
 type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Status  int    `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+
+	// cause is logged server-side but never sent to clients
+	cause error
 }
 
 // Error implements the error interface
 func (e *APIError) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
 	return e.Message
 }
 
-// Predefined API errors
+// Unwrap returns the underlying error
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// Is matches API errors by code, so errors.Is(err, ErrImageTooLarge) holds for copies
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// WithCause returns a copy of e wrapping the underlying error
+func (e *APIError) WithCause(err error) *APIError {
+	c := *e
+	c.cause = err
+	return &c
+}
+
+// WithDetail returns a copy of e with a client-facing explanation of this occurrence
+func (e *APIError) WithDetail(format string, args ...interface{}) *APIError {
+	c := *e
+	c.Detail = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// ErrorResponse is the default JSON body of an error response
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Detail    string `json:"detail,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProblemDetails is an RFC 7807 error body, sent when the client accepts application/problem+json
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Predefined API errors: request validation
+var (
+	ErrInvalidRequest      = &APIError{Code: "INVALID_REQUEST", Message: "Invalid JSON request", Status: 400}
+	ErrInvalidBatch        = &APIError{Code: "INVALID_BATCH", Message: "Invalid batch request", Status: 400}
+	ErrRequestTooLarge     = &APIError{Code: "REQUEST_TOO_LARGE", Message: "Request body exceeds maximum size", Status: 413}
+	ErrMissingImageURL     = &APIError{Code: "MISSING_IMAGE_URL", Message: "Image URL is required", Status: 400}
+	ErrMissingImage        = &APIError{Code: "MISSING_IMAGE", Message: "Image URL or image data is required", Status: 400}
+	ErrMissingImages       = &APIError{Code: "MISSING_IMAGES", Message: "At least one image is required", Status: 400}
+	ErrTooManyImages       = &APIError{Code: "TOO_MANY_IMAGES", Message: "Batch exceeds the maximum number of images", Status: 400}
+	ErrInvalidImageData    = &APIError{Code: "INVALID_IMAGE_DATA", Message: "Invalid base64 image data", Status: 400}
+	ErrInvalidOutputFormat = &APIError{Code: "INVALID_OUTPUT_FORMAT", Message: "Output format must be image or svg", Status: 400}
+	ErrInvalidColor        = &APIError{Code: "INVALID_COLOR", Message: "Invalid circle color", Status: 400}
+	ErrInvalidDetector     = &APIError{Code: "INVALID_DETECTOR", Message: "Unknown detector, cascade or profile", Status: 400}
+	ErrInvalidStream       = &APIError{Code: "INVALID_STREAM", Message: "Failed to read batch stream", Status: 400}
+	ErrCallbacksDisabled   = &APIError{Code: "CALLBACKS_DISABLED", Message: "Callbacks are not enabled on this server", Status: 400}
+	ErrInvalidCallbackURL  = &APIError{Code: "INVALID_CALLBACK_URL", Message: "Invalid callback URL", Status: 400}
+	ErrInvalidJobType      = &APIError{Code: "INVALID_JOB_TYPE", Message: "Job type must be detect, validate or visual", Status: 400}
+)
+
+// Predefined API errors: image retrieval and decoding
+var (
+	ErrInvalidURL      = &APIError{Code: "INVALID_URL", Message: "Invalid image URL", Status: 400}
+	ErrURLNotAllowed   = &APIError{Code: "URL_NOT_ALLOWED", Message: "Image URL is not allowed", Status: 422}
+	ErrImageNotFound   = &APIError{Code: "IMAGE_NOT_FOUND", Message: "Image not found at URL", Status: 422}
+	ErrImageDownload   = &APIError{Code: "IMAGE_DOWNLOAD_FAILED", Message: "Failed to download image", Status: 502}
+	ErrDownloadTimeout = &APIError{Code: "IMAGE_DOWNLOAD_TIMEOUT", Message: "Timed out downloading image", Status: 504}
+	ErrImageTooLarge   = &APIError{Code: "IMAGE_TOO_LARGE", Message: "Image size exceeds maximum limit", Status: 413}
+	ErrImageDimensions = &APIError{Code: "IMAGE_DIMENSIONS_TOO_LARGE", Message: "Image dimensions exceed maximum limit", Status: 422}
+	ErrImageFormat     = &APIError{Code: "UNSUPPORTED_IMAGE_FORMAT", Message: "Unsupported image format", Status: 415}
+	ErrImageDecode     = &APIError{Code: "IMAGE_DECODE_FAILED", Message: "Failed to decode image", Status: 422}
+)
+
+// Predefined API errors: processing
+var (
+	ErrFaceDetection        = &APIError{Code: "FACE_DETECTION_FAILED", Message: "Face detection failed", Status: 500}
+	ErrImageProcessing      = &APIError{Code: "IMAGE_PROCESSING_FAILED", Message: "Failed to process image", Status: 500}
+	ErrServerBusy           = &APIError{Code: "SERVER_BUSY", Message: "Server is busy, retry later", Status: 503}
+	ErrTimeout              = &APIError{Code: "TIMEOUT", Message: "Processing timed out", Status: 504}
+	ErrRateLimited          = &APIError{Code: "RATE_LIMITED", Message: "Rate limit exceeded, retry later", Status: 429}
+	ErrQuotaExceeded        = &APIError{Code: "QUOTA_EXCEEDED", Message: "Request quota exhausted", Status: 429}
+	ErrStreamingUnsupported = &APIError{Code: "STREAMING_UNSUPPORTED", Message: "Streaming is not supported", Status: 500}
+	ErrInternal             = &APIError{Code: "INTERNAL_ERROR", Message: "Internal server error", Status: 500}
+)
+
+// Predefined API errors: authentication
+var (
+	ErrUnauthorized = &APIError{Code: "UNAUTHORIZED", Message: "Missing or invalid credentials", Status: 401}
+	ErrForbidden    = &APIError{Code: "FORBIDDEN", Message: "Credentials lack the required scope", Status: 403}
+)
+
+// Predefined API errors: admin API
+var (
+	ErrInvalidConfig   = &APIError{Code: "INVALID_CONFIG", Message: "Invalid configuration", Status: 400}
+	ErrInvalidLogLevel = &APIError{Code: "INVALID_LOG_LEVEL", Message: "Log level must be one of trace, debug, info, warn, error, fatal or panic", Status: 400}
+	ErrCascadeReload   = &APIError{Code: "CASCADE_RELOAD_FAILED", Message: "Failed to reload the detection cascade", Status: 500}
+	ErrRateLimitReset  = &APIError{Code: "RATE_LIMIT_RESET_FAILED", Message: "Failed to reset rate limits", Status: 500}
+	ErrRateLimitOff    = &APIError{Code: "RATE_LIMITING_DISABLED", Message: "Rate limiting is not enabled on this server", Status: 409}
+)
+
+// Predefined API errors: asynchronous jobs
 var (
-	ErrInvalidURL     = &APIError{Code: "INVALID_URL", Message: "Invalid image URL", Status: 400}
-	ErrImageDownload  = &APIError{Code: "IMAGE_DOWNLOAD_ERROR", Message: "Failed to download image", Status: 400}
-	ErrImageFormat    = &APIError{Code: "INVALID_IMAGE_FORMAT", Message: "Unsupported image format", Status: 400}
-	ErrFaceDetection  = &APIError{Code: "FACE_DETECTION_ERROR", Message: "Face detection failed", Status: 500}
-	ErrImageTooLarge  = &APIError{Code: "IMAGE_TOO_LARGE", Message: "Image size exceeds maximum limit", Status: 400}
-	ErrInvalidRequest = &APIError{Code: "INVALID_REQUEST", Message: "Invalid JSON request", Status: 400}
-)
\ No newline at end of file
+	ErrJobNotFound     = &APIError{Code: "JOB_NOT_FOUND", Message: "Job not found", Status: 404}
+	ErrJobFinished     = &APIError{Code: "JOB_FINISHED", Message: "Job already finished", Status: 409}
+	ErrJobQueueFull    = &APIError{Code: "QUEUE_FULL", Message: "Job queue is full", Status: 503}
+	ErrShuttingDown    = &APIError{Code: "SHUTTING_DOWN", Message: "Server is shutting down, retry on another instance", Status: 503}
+	ErrJobTimeout      = &APIError{Code: "JOB_TIMEOUT", Message: "Job timed out", Status: 504}
+	ErrJobCreateFailed = &APIError{Code: "JOB_CREATE_FAILED", Message: "Failed to create job", Status: 500}
+	ErrJobLookupFailed = &APIError{Code: "JOB_LOOKUP_FAILED", Message: "Failed to load job", Status: 500}
+)
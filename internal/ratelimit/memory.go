@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets and expired quotas are dropped
+const sweepInterval = time.Minute
+
+// tokenBucket is the state of one bucket
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	rate    float64
+	burst   int
+}
+
+// refill adds the tokens earned since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.burst), b.tokens+elapsed*b.rate)
+	}
+	b.updated = now
+}
+
+// quotaCounter is the state of one quota window
+type quotaCounter struct {
+	count int64
+	reset time.Time
+}
+
+// MemoryBackend keeps limiter state in process memory. Limits are per replica and reset
+// on restart.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	quotas    map[string]*quotaCounter
+	nextSweep time.Time
+}
+
+// NewMemoryBackend creates a new in-memory limiter backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*tokenBucket),
+		quotas:  make(map[string]*quotaCounter),
+	}
+}
+
+// TakeToken removes one token from the bucket stored under key
+func (m *MemoryBackend) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		m.buckets[key] = b
+	}
+	b.rate, b.burst = rate, burst
+	b.refill(now)
+
+	decision := Decision{Limit: int64(burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	decision.Remaining = int64(b.tokens)
+	decision.Reset = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return decision, nil
+}
+
+// ConsumeQuotas counts one request against every quota if all of them have room left
+func (m *MemoryBackend) ConsumeQuotas(ctx context.Context, quotas []Quota, now time.Time) ([]Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	counters := make([]*quotaCounter, len(quotas))
+	allowed := true
+	for i, q := range quotas {
+		c, ok := m.quotas[q.Key]
+		if !ok {
+			c = &quotaCounter{reset: q.Reset}
+			m.quotas[q.Key] = c
+		}
+		counters[i] = c
+		if c.count >= q.Limit {
+			allowed = false
+		}
+	}
+
+	decisions := make([]Decision, len(quotas))
+	for i, q := range quotas {
+		c := counters[i]
+		if allowed {
+			c.count++
+		}
+		decisions[i] = Decision{
+			Allowed:   allowed || c.count < q.Limit,
+			Limit:     q.Limit,
+			Remaining: max(q.Limit-c.count, 0),
+			Reset:     c.reset,
+		}
+		if !decisions[i].Allowed {
+			decisions[i].RetryAfter = c.reset.Sub(now)
+		}
+	}
+	return decisions, nil
+}
+
+// ResetBuckets drops the buckets of client, or all buckets when client is empty; a missing
+// bucket starts out full
+func (m *MemoryBackend) ResetBuckets(ctx context.Context, client string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reset := 0
+	for key := range m.buckets {
+		if client == "" || bucketClient(key) == client {
+			delete(m.buckets, key)
+			reset++
+		}
+	}
+	return reset, nil
+}
+
+// Stats reports the number of stored buckets and quota counters
+func (m *MemoryBackend) Stats(ctx context.Context) (BackendStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return BackendStats{Buckets: len(m.buckets), Quotas: len(m.quotas)}, nil
+}
+
+// sweep drops full buckets and expired quota windows, which behave exactly like missing
+// ones. Callers hold m.mu.
+func (m *MemoryBackend) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	m.nextSweep = now.Add(sweepInterval)
+
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.rate >= float64(b.burst) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, c := range m.quotas {
+		if !now.Before(c.reset) {
+			delete(m.quotas, key)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"face-recognition-api/internal/config"
+)
+
+// Class groups endpoints sharing a token bucket
+type Class string
+
+// Endpoint classes. Every class has its own bucket per client; all classes except
+// ClassStatus also count against the client's daily and monthly quotas.
+const (
+	ClassDetect Class = "detect"
+	ClassBatch  Class = "batch"
+	ClassVisual Class = "visual"
+	ClassStatus Class = "status"
+)
+
+// Decision is the outcome of checking one limit
+type Decision struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// Quota is a fixed-window request counter
+type Quota struct {
+	Key   string
+	Limit int64
+	Reset time.Time
+}
+
+// Backend stores limiter state. Implementations must be safe for concurrent use; a shared
+// store lets several replicas enforce the same limits.
+type Backend interface {
+	// TakeToken removes one token from the bucket stored under key, which refills at rate
+	// tokens per second up to burst
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (Decision, error)
+	// ConsumeQuotas counts one request against every quota, but only if all of them have
+	// room left. Decisions are returned in the order of quotas.
+	ConsumeQuotas(ctx context.Context, quotas []Quota, now time.Time) ([]Decision, error)
+	// ResetBuckets refills the token buckets of client, or of every client when client is
+	// empty, and returns how many were reset. Quotas are kept.
+	ResetBuckets(ctx context.Context, client string) (int, error)
+	// Stats reports how much limiter state is stored
+	Stats(ctx context.Context) (BackendStats, error)
+}
+
+// BackendStats describes the state held by a backend
+type BackendStats struct {
+	Buckets int `json:"buckets"`
+	Quotas  int `json:"quotas"`
+}
+
+// bucket is the token bucket configuration of a class
+type bucket struct {
+	rate  float64
+	burst int
+}
+
+// Reason explains which limit denied a request
+type Reason string
+
+// Denial reasons
+const (
+	ReasonRate    Reason = "rate"
+	ReasonDaily   Reason = "daily"
+	ReasonMonthly Reason = "monthly"
+)
+
+// Result is the combined outcome of the rate and quota checks for one request. Decision
+// describes the most constraining limit, or the one that denied the request.
+type Result struct {
+	Decision
+	Reason Reason
+}
+
+// limits are the rates and quotas a limiter enforces
+type limits struct {
+	buckets map[Class]bucket
+	daily   int64
+	monthly int64
+}
+
+// Limiter enforces per-class token buckets and daily and monthly quotas per client
+type Limiter struct {
+	backend Backend
+	limits  atomic.Pointer[limits]
+}
+
+// New creates a new limiter; a zero rate or quota disables that limit
+func New(cfg config.RateLimitConfig, backend Backend) *Limiter {
+	l := &Limiter{backend: backend}
+	l.Update(cfg)
+	return l
+}
+
+// Update replaces the rates and quotas of l. Existing buckets and quota counters are kept,
+// so clients don't get a fresh allowance.
+func (l *Limiter) Update(cfg config.RateLimitConfig) {
+	l.limits.Store(&limits{
+		buckets: map[Class]bucket{
+			ClassDetect: {rate: cfg.DetectRate, burst: cfg.DetectBurst},
+			ClassBatch:  {rate: cfg.BatchRate, burst: cfg.BatchBurst},
+			ClassVisual: {rate: cfg.VisualRate, burst: cfg.VisualBurst},
+			ClassStatus: {rate: cfg.StatusRate, burst: cfg.StatusBurst},
+		},
+		daily:   cfg.DailyQuota,
+		monthly: cfg.MonthlyQuota,
+	})
+}
+
+// Allow checks and consumes the limits of class for client. Quotas are only consumed by
+// requests the token bucket lets through.
+func (l *Limiter) Allow(ctx context.Context, client string, class Class, now time.Time) (Result, error) {
+	limits := l.limits.Load()
+
+	var result Result
+	result.Allowed = true
+
+	if b := limits.buckets[class]; b.rate > 0 {
+		decision, err := l.backend.TakeToken(ctx, bucketKey(class, client), b.rate, max(b.burst, 1), now)
+		if err != nil {
+			return result, err
+		}
+		result.Decision = decision
+		if !decision.Allowed {
+			result.Reason = ReasonRate
+			return result, nil
+		}
+	}
+
+	if class == ClassStatus {
+		return result, nil
+	}
+
+	var quotas []Quota
+	var reasons []Reason
+	now = now.UTC()
+	if limits.daily > 0 {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		quotas = append(quotas, Quota{Key: "daily:" + day.Format("2006-01-02") + ":" + client, Limit: limits.daily, Reset: day.AddDate(0, 0, 1)})
+		reasons = append(reasons, ReasonDaily)
+	}
+	if limits.monthly > 0 {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		quotas = append(quotas, Quota{Key: "monthly:" + month.Format("2006-01") + ":" + client, Limit: limits.monthly, Reset: month.AddDate(0, 1, 0)})
+		reasons = append(reasons, ReasonMonthly)
+	}
+	if len(quotas) == 0 {
+		return result, nil
+	}
+
+	decisions, err := l.backend.ConsumeQuotas(ctx, quotas, now)
+	if err != nil {
+		return result, err
+	}
+	for i, decision := range decisions {
+		if !decision.Allowed {
+			return Result{Decision: decision, Reason: reasons[i]}, nil
+		}
+		if result.Limit == 0 || decision.Remaining < result.Remaining {
+			result.Decision = decision
+		}
+	}
+	return result, nil
+}
+
+// Reset refills the token buckets of client in every class, or of every client when client
+// is empty, and returns how many buckets were reset
+func (l *Limiter) Reset(ctx context.Context, client string) (int, error) {
+	return l.backend.ResetBuckets(ctx, client)
+}
+
+// Stats reports how much limiter state the backend holds
+func (l *Limiter) Stats(ctx context.Context) (BackendStats, error) {
+	return l.backend.Stats(ctx)
+}
+
+// bucketKey is the backend key of the token bucket of client in class
+func bucketKey(class Class, client string) string {
+	return "rate:" + string(class) + ":" + client
+}
+
+// bucketClient returns the client a bucket key belongs to
+func bucketClient(key string) string {
+	_, rest, _ := strings.Cut(key, ":")
+	_, client, _ := strings.Cut(rest, ":")
+	return client
+}
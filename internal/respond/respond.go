@@ -0,0 +1,83 @@
+package respond
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/requestid"
+)
+
+// ProblemContentType is the RFC 7807 media type for problem details
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix namespaces problem type URIs by API error code
+const problemTypePrefix = "urn:face-recognition-api:error:"
+
+// JSON writes v as a JSON response with the given status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error writes err as a structured error response. Errors that are not an *models.APIError
+// are reported as INTERNAL_ERROR. The underlying cause, if any, is logged and never sent to
+// the client. Clients that accept application/problem+json get RFC 7807 problem details.
+func Error(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, err error) {
+	apiErr := AsAPIError(err)
+
+	if cause := apiErr.Unwrap(); cause != nil {
+		logger.WithContext(r.Context()).WithError(cause).WithField("code", apiErr.Code).Error(apiErr.Message)
+	}
+	metrics.Errors.WithLabelValues(apiErr.Code).Inc()
+
+	requestID := requestid.FromContext(r.Context())
+	if !acceptsProblem(r) {
+		JSON(w, apiErr.Status, models.ErrorResponse{
+			Error:     apiErr.Message,
+			Code:      apiErr.Code,
+			Detail:    apiErr.Detail,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(models.ProblemDetails{
+		Type:      problemTypePrefix + apiErr.Code,
+		Title:     apiErr.Message,
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: requestID,
+	})
+}
+
+// AsAPIError returns the *models.APIError in err's chain, or INTERNAL_ERROR wrapping err
+func AsAPIError(err error) *models.APIError {
+	var apiErr *models.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return models.ErrInternal.WithCause(err)
+}
+
+// acceptsProblem reports whether the Accept header lists application/problem+json
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == ProblemContentType && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"image"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+)
+
+// ErrDetectorBusy is returned when the detection queue is full or the wait for a worker times out
+var ErrDetectorBusy = errors.New("face detector is busy")
+
+// DetectionPool bounds CPU-bound face detection to a fixed number of workers with a bounded
+// wait queue, so bursts of large images are shed instead of slowing every request down.
+type DetectionPool struct {
+	config config.PoolConfig
+	logger *logrus.Logger
+
+	// admitted holds a token for every running or waiting detection
+	admitted chan struct{}
+	// workers holds a token for every running detection
+	workers chan struct{}
+}
+
+// NewDetectionPool creates a new detection pool instance; zero workers means GOMAXPROCS
+func NewDetectionPool(cfg config.PoolConfig, logger *logrus.Logger) *DetectionPool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+
+	return &DetectionPool{
+		config:   cfg,
+		logger:   logger,
+		admitted: make(chan struct{}, cfg.Workers+cfg.QueueSize),
+		workers:  make(chan struct{}, cfg.Workers),
+	}
+}
+
+// DetectFaces runs face detection with detector on a pool worker. It fails fast with
+// ErrDetectorBusy when the wait queue is full and gives up with ErrDetectorBusy after
+// waiting QueueTimeout.
+func (p *DetectionPool) DetectFaces(ctx context.Context, detector Detector, img image.Image) ([]models.Face, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	detectStart := time.Now()
+	faces, err := detector.DetectFaces(img)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.DetectionDuration.WithLabelValues(detector.Name()).Observe(time.Since(detectStart).Seconds())
+	metrics.FacesPerImage.WithLabelValues(detector.Name()).Observe(float64(len(faces)))
+	return faces, nil
+}
+
+// LocateLandmarks runs LocateLandmarks on a pool worker, queueing and failing the same way
+// as DetectFaces
+func (p *DetectionPool) LocateLandmarks(ctx context.Context, img image.Image, faces []models.Face) ([]models.Face, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return LocateLandmarks(img, faces)
+}
+
+// acquire waits for a worker and returns the function that frees it. It fails fast with
+// ErrDetectorBusy when the wait queue is full and gives up with ErrDetectorBusy after
+// waiting QueueTimeout.
+func (p *DetectionPool) acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		metrics.DetectionRejected.WithLabelValues("queue_full").Inc()
+		return nil, ErrDetectorBusy
+	}
+
+	start := time.Now()
+	metrics.DetectionQueueDepth.Inc()
+
+	timer := time.NewTimer(p.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.workers <- struct{}{}:
+	case <-timer.C:
+		<-p.admitted
+		metrics.DetectionQueueDepth.Dec()
+		metrics.DetectionRejected.WithLabelValues("queue_timeout").Inc()
+		return nil, ErrDetectorBusy
+	case <-ctx.Done():
+		<-p.admitted
+		metrics.DetectionQueueDepth.Dec()
+		metrics.DetectionRejected.WithLabelValues("canceled").Inc()
+		return nil, ctx.Err()
+	}
+
+	wait := time.Since(start)
+	metrics.DetectionQueueDepth.Dec()
+	metrics.DetectionQueueWait.Observe(wait.Seconds())
+	trace.SpanFromContext(ctx).AddEvent("detection worker acquired",
+		trace.WithAttributes(attribute.Int64("detection.queue_wait_ms", wait.Milliseconds())))
+
+	metrics.DetectionWorkersBusy.Inc()
+	return func() {
+		metrics.DetectionWorkersBusy.Dec()
+		<-p.workers
+		<-p.admitted
+	}, nil
+}
+
+// RetryAfter returns how long clients should wait before retrying a rejected request
+func (p *DetectionPool) RetryAfter() time.Duration {
+	return p.config.RetryAfter
+}
+
+// Stats returns the number of busy workers, waiting detections and the pool size
+func (p *DetectionPool) Stats() (busy, waiting, workers int) {
+	busy = len(p.workers)
+	waiting = max(len(p.admitted)-busy, 0)
+	return busy, waiting, p.config.Workers
+}
+
+// Saturation returns the fraction of the pool's capacity, workers plus wait queue, in use
+func (p *DetectionPool) Saturation() float64 {
+	return float64(len(p.admitted)) / float64(cap(p.admitted))
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"runtime/debug"
+	"strings"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/models"
+)
+
+// Detector finds faces in images. Implementations must be safe for concurrent use and
+// leave metrics to the detection pool.
+type Detector interface {
+	// Name identifies the backend in configuration, requests and responses
+	Name() string
+	// Version identifies the implementation or model the backend runs
+	Version() string
+	Capabilities() DetectorCapabilities
+	DetectFaces(img image.Image) ([]models.Face, error)
+}
+
+// DetectorCapabilities describes what a backend can find and how to read its confidence
+// scores, which are not comparable across backends
+type DetectorCapabilities struct {
+	// MinFaceSize is the smallest face side, in pixels, the backend looks for
+	MinFaceSize int `json:"min_face_size"`
+	// MaxConfidence bounds confidence scores; zero means they are unbounded
+	MaxConfidence float32 `json:"max_confidence,omitempty"`
+	// LowConfidence is the score below which a detection is considered unreliable
+	LowConfidence float32 `json:"low_confidence"`
+	// Grayscale reports whether faces are found in images without color
+	Grayscale bool `json:"grayscale"`
+	// Tunable reports whether parameters can be changed through the admin API
+	Tunable bool `json:"tunable"`
+}
+
+// CascadeSelector is implemented by backends that can run one of several named cascades
+type CascadeSelector interface {
+	// Cascades returns the names of the loaded cascades
+	Cascades() []string
+	// WithCascade returns the backend bound to the named cascade
+	WithCascade(name string) (Detector, error)
+}
+
+// Detector selection errors
+var (
+	ErrUnknownDetector  = errors.New("unknown detector")
+	ErrUnknownProfile   = errors.New("unknown profile")
+	ErrDetectorConflict = errors.New("a profile can't be combined with a detector or cascade")
+)
+
+// DetectorRegistry holds the available detection backends and the profiles that select them
+type DetectorRegistry struct {
+	detectors map[string]Detector
+	names     []string
+	fallback  string
+	profiles  map[string]models.DetectorSelection
+}
+
+// NewDetectorRegistry creates a registry of detectors, failing when the configured default
+// backend or a profile names a detector that isn't registered
+func NewDetectorRegistry(cfg config.DetectionConfig, detectors ...Detector) (*DetectorRegistry, error) {
+	r := &DetectorRegistry{
+		detectors: make(map[string]Detector, len(detectors)),
+		fallback:  cfg.Backend,
+		profiles:  make(map[string]models.DetectorSelection, len(cfg.Profiles)),
+	}
+	for _, d := range detectors {
+		r.detectors[d.Name()] = d
+		r.names = append(r.names, d.Name())
+	}
+
+	if _, ok := r.detectors[cfg.Backend]; !ok {
+		return nil, fmt.Errorf("%w: default backend %s", ErrUnknownDetector, cfg.Backend)
+	}
+	for profile, selection := range cfg.Profiles {
+		backend, cascade, _ := strings.Cut(selection, ":")
+		sel := models.DetectorSelection{Detector: backend, Cascade: cascade}
+		if _, err := r.resolve(sel); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profile, err)
+		}
+		r.profiles[profile] = sel
+	}
+	return r, nil
+}
+
+// Select returns the detector for a request: the named backend, the backend of the named
+// profile, or the default when neither is given, bound to the selected cascade if any
+func (r *DetectorRegistry) Select(sel models.DetectorSelection) (Detector, error) {
+	if sel.Profile != "" {
+		if sel.Detector != "" || sel.Cascade != "" {
+			return nil, ErrDetectorConflict
+		}
+		profile, ok := r.profiles[sel.Profile]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, sel.Profile)
+		}
+		sel = profile
+	}
+	return r.resolve(sel)
+}
+
+// resolve returns the named backend, or the default, bound to the selected cascade if any
+func (r *DetectorRegistry) resolve(sel models.DetectorSelection) (Detector, error) {
+	name := sel.Detector
+	if name == "" {
+		name = r.fallback
+	}
+	d, ok := r.detectors[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDetector, name)
+	}
+	if sel.Cascade == "" {
+		return d, nil
+	}
+
+	cascades, ok := d.(CascadeSelector)
+	if !ok {
+		return nil, fmt.Errorf("%w: backend %s doesn't use cascades", ErrUnknownCascade, name)
+	}
+	return cascades.WithCascade(sel.Cascade)
+}
+
+// Default returns the detector used when a request doesn't select one
+func (r *DetectorRegistry) Default() Detector {
+	return r.detectors[r.fallback]
+}
+
+// Detectors returns every registered detector in registration order
+func (r *DetectorRegistry) Detectors() []Detector {
+	detectors := make([]Detector, len(r.names))
+	for i, name := range r.names {
+		detectors[i] = r.detectors[name]
+	}
+	return detectors
+}
+
+// Profiles returns the backend, and cascade if any, each profile selects
+func (r *DetectorRegistry) Profiles() map[string]models.DetectorSelection {
+	profiles := make(map[string]models.DetectorSelection, len(r.profiles))
+	for name, sel := range r.profiles {
+		profiles[name] = sel
+	}
+	return profiles
+}
+
+// moduleVersion returns the version of a dependency compiled into the binary
+func moduleVersion(path string) string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == path {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
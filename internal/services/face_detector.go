@@ -1,8 +1,15 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	_ "embed"
 
 	"github.com/esimov/pigo/core"
@@ -16,33 +23,312 @@ import (
 //go:embed facefinder
 var cascadeFile []byte
 
-// FaceDetector wraps the pigo face detection library
+// embeddedCascade names the compiled-in face cascade
+const embeddedCascade = "facefinder"
+
+// ErrUnknownCascade is returned when a request selects a cascade that isn't loaded
+var ErrUnknownCascade = errors.New("unknown cascade")
+
+// detectorState is the loaded cascades together with the parameters they run with,
+// swapped as a unit
+type detectorState struct {
+	cascades map[string]*pigo.Pigo
+	fallback string // cascade used when a request selects none
+	config   config.PigoConfig
+}
+
+// FaceDetector is the pigo detection backend, running a pixel intensity comparison
+// cascade on grayscale images
 type FaceDetector struct {
-	classifier *pigo.Pigo
-	config     config.PigoConfig
-	logger     *logrus.Logger
+	state  atomic.Pointer[detectorState]
+	swap   sync.Mutex // serializes state changes; detections only load state
+	logger *logrus.Logger
 }
 
 // NewFaceDetector creates a new face detector instance
 func NewFaceDetector(cfg config.PigoConfig, logger *logrus.Logger) (*FaceDetector, error) {
-	// Initialize pigo classifier
-	p := pigo.NewPigo()
-	
-	// Parse the cascade file
-	classifier, err := p.Unpack(cascadeFile)
+	cascades, fallback, err := LoadCascades(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &FaceDetector{logger: logger}
+	fd.state.Store(&detectorState{cascades: cascades, fallback: fallback, config: cfg})
+	return fd, nil
+}
+
+// LoadCascades loads the embedded cascade, the cascade file and every file in the cascade
+// directory, each named after its file without the extension. Every file must parse. The
+// cascade file, or the embedded cascade when there is none, is the fallback for requests
+// that don't select one.
+func LoadCascades(cfg config.PigoConfig) (map[string]*pigo.Pigo, string, error) {
+	embedded, err := unpackCascade(cascadeFile)
+	if err != nil {
+		return nil, "", err
+	}
+	cascades := map[string]*pigo.Pigo{embeddedCascade: embedded}
+	paths := make(map[string]string)
+
+	add := func(path string) (string, error) {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if name == embeddedCascade {
+			return "", fmt.Errorf("cascade file %s: name %s is reserved for the embedded cascade", path, name)
+		}
+		if existing, ok := paths[name]; ok {
+			if filepath.Clean(existing) == filepath.Clean(path) {
+				return name, nil
+			}
+			return "", fmt.Errorf("cascade file %s: name %s is already used by %s", path, name, existing)
+		}
+
+		classifier, err := LoadCascade(path)
+		if err != nil {
+			return "", fmt.Errorf("cascade file %s: %w", path, err)
+		}
+		cascades[name], paths[name] = classifier, path
+		return name, nil
+	}
+
+	fallback := embeddedCascade
+	if cfg.CascadeFile != "" {
+		if fallback, err = add(cfg.CascadeFile); err != nil {
+			return nil, "", err
+		}
+	}
+	if cfg.CascadeDir != "" {
+		entries, err := os.ReadDir(cfg.CascadeDir)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read cascade directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if _, err := add(filepath.Join(cfg.CascadeDir, entry.Name())); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	return cascades, fallback, nil
+}
+
+// LoadCascade reads and parses a pigo cascade file; an empty path uses the embedded cascade
+func LoadCascade(path string) (*pigo.Pigo, error) {
+	data := cascadeFile
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read cascade file: %w", err)
+		}
+	}
+	return unpackCascade(data)
+}
+
+// unpackCascade parses cascade data. Unpack indexes the data without bounds checks, so a
+// truncated or foreign file panics instead of returning an error.
+func unpackCascade(data []byte) (classifier *pigo.Pigo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			classifier, err = nil, fmt.Errorf("failed to parse cascade file: malformed cascade: %v", r)
+		}
+	}()
+
+	classifier, err = pigo.NewPigo().Unpack(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse cascade file: %w", err)
 	}
+	return classifier, nil
+}
+
+// pigoVersion is the pigo release compiled into the binary
+var pigoVersion = moduleVersion("github.com/esimov/pigo")
+
+// Name returns the backend name, pigo
+func (fd *FaceDetector) Name() string {
+	return "pigo"
+}
+
+// Version returns the pigo release and the fallback cascade
+func (fd *FaceDetector) Version() string {
+	return pigoVersion + "+" + fd.Fallback()
+}
+
+// Fallback returns the name of the cascade used when a request selects none
+func (fd *FaceDetector) Fallback() string {
+	return fd.state.Load().fallback
+}
+
+// Capabilities describes the pigo backend. Confidence scores are unbounded cascade scores.
+func (fd *FaceDetector) Capabilities() DetectorCapabilities {
+	return DetectorCapabilities{
+		MinFaceSize:   fd.Config().MinSize,
+		LowConfidence: 10,
+		Grayscale:     true,
+		Tunable:       true,
+	}
+}
+
+// Config returns the current detection parameters
+func (fd *FaceDetector) Config() config.PigoConfig {
+	return fd.state.Load().config
+}
+
+// Cascades returns the names of the loaded cascades in sorted order
+func (fd *FaceDetector) Cascades() []string {
+	state := fd.state.Load()
+	names := make([]string, 0, len(state.cascades))
+	for name := range state.cascades {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithCascade returns the pigo backend bound to the named cascade
+func (fd *FaceDetector) WithCascade(name string) (Detector, error) {
+	if _, ok := fd.state.Load().cascades[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCascade, name)
+	}
+	return pigoCascade{fd: fd, name: name}, nil
+}
+
+// WithConfig returns the pigo backend running the fallback cascade with the detection
+// parameters in cfg rather than the current ones. The cascade settings in cfg are ignored,
+// so cascade reloads still apply.
+func (fd *FaceDetector) WithConfig(cfg config.PigoConfig) Detector {
+	return pigoParams{fd: fd, config: cfg}
+}
 
-	return &FaceDetector{
-		classifier: classifier,
-		config:     cfg,
-		logger:     logger,
+// SetConfig replaces the detection parameters, loading the cascades first when the cascade
+// file or directory changed. Detections already running keep the old parameters; on error
+// nothing changes.
+func (fd *FaceDetector) SetConfig(cfg config.PigoConfig) error {
+	apply, err := fd.PrepareConfig(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// PrepareConfig does the part of SetConfig that can fail, loading the cascades when the
+// cascade file or directory changed, and returns a function that swaps the parameters in.
+// Nothing changes until it is called, which lets callers prepare several detectors before
+// changing any of them.
+func (fd *FaceDetector) PrepareConfig(cfg config.PigoConfig) (apply func(), err error) {
+	current := fd.state.Load()
+	var loaded *detectorState
+	if cfg.CascadeFile != current.config.CascadeFile || cfg.CascadeDir != current.config.CascadeDir {
+		loaded = &detectorState{config: cfg}
+		if loaded.cascades, loaded.fallback, err = LoadCascades(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() {
+		fd.swap.Lock()
+		defer fd.swap.Unlock()
+
+		if loaded != nil {
+			fd.state.Store(loaded)
+			return
+		}
+		// cfg leaves the cascades alone, so keep those current at swap time, which a reload
+		// or another change may have replaced since
+		latest := fd.state.Load()
+		next := cfg
+		next.CascadeFile, next.CascadeDir = latest.config.CascadeFile, latest.config.CascadeDir
+		fd.state.Store(&detectorState{cascades: latest.cascades, fallback: latest.fallback, config: next})
 	}, nil
 }
 
-// DetectFaces detects faces in the given image and returns face coordinates
+// ReloadCascades reads the cascade file and directory again, so replaced, added and
+// removed files take effect
+func (fd *FaceDetector) ReloadCascades() error {
+	fd.swap.Lock()
+	defer fd.swap.Unlock()
+
+	current := fd.state.Load()
+	cascades, fallback, err := LoadCascades(current.config)
+	if err != nil {
+		return err
+	}
+
+	fd.state.Store(&detectorState{cascades: cascades, fallback: fallback, config: current.config})
+	return nil
+}
+
+// pigoCascade is the pigo backend bound to one named cascade
+type pigoCascade struct {
+	fd   *FaceDetector
+	name string
+}
+
+func (c pigoCascade) Name() string {
+	return c.fd.Name()
+}
+
+func (c pigoCascade) Version() string {
+	return pigoVersion + "+" + c.name
+}
+
+func (c pigoCascade) Capabilities() DetectorCapabilities {
+	return c.fd.Capabilities()
+}
+
+func (c pigoCascade) DetectFaces(img image.Image) ([]models.Face, error) {
+	return c.fd.detect(c.name, img)
+}
+
+// pigoParams is the pigo backend running its fallback cascade with fixed parameters
+type pigoParams struct {
+	fd     *FaceDetector
+	config config.PigoConfig
+}
+
+func (p pigoParams) Name() string {
+	return p.fd.Name()
+}
+
+func (p pigoParams) Version() string {
+	return p.fd.Version()
+}
+
+func (p pigoParams) Capabilities() DetectorCapabilities {
+	capabilities := p.fd.Capabilities()
+	capabilities.MinFaceSize = p.config.MinSize
+	return capabilities
+}
+
+func (p pigoParams) DetectFaces(img image.Image) ([]models.Face, error) {
+	return p.fd.detectWith(p.fd.state.Load(), p.config, "", img)
+}
+
+// DetectFaces detects faces in the given image with the fallback cascade and returns face
+// coordinates
 func (fd *FaceDetector) DetectFaces(img image.Image) ([]models.Face, error) {
+	return fd.detect("", img)
+}
+
+// detect runs the named cascade, or the fallback cascade when name is empty, on img
+func (fd *FaceDetector) detect(name string, img image.Image) ([]models.Face, error) {
+	state := fd.state.Load()
+	return fd.detectWith(state, state.config, name, img)
+}
+
+// detectWith runs the named cascade of state, or its fallback cascade when name is empty,
+// on img with the detection parameters in cfg
+func (fd *FaceDetector) detectWith(state *detectorState, cfg config.PigoConfig, name string, img image.Image) ([]models.Face, error) {
+	if name == "" {
+		name = state.fallback
+	}
+	classifier, ok := state.cascades[name]
+	if !ok {
+		// The cascade was removed by a reload after the request selected it
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCascade, name)
+	}
+
 	// Convert image to grayscale using pigo's utility
 	pixels := pigo.RgbToGrayscale(img)
 	
@@ -52,10 +338,10 @@ func (fd *FaceDetector) DetectFaces(img image.Image) ([]models.Face, error) {
 	
 	// Set up cascade parameters
 	cParams := pigo.CascadeParams{
-		MinSize:     fd.config.MinSize,
-		MaxSize:     fd.config.MaxSize,
-		ShiftFactor: float64(fd.config.ShiftFactor),
-		ScaleFactor: float64(fd.config.ScaleFactor),
+		MinSize:     cfg.MinSize,
+		MaxSize:     cfg.MaxSize,
+		ShiftFactor: float64(cfg.ShiftFactor),
+		ScaleFactor: float64(cfg.ScaleFactor),
 		ImageParams: pigo.ImageParams{
 			Pixels: pixels,
 			Rows:   height,
@@ -66,15 +352,15 @@ func (fd *FaceDetector) DetectFaces(img image.Image) ([]models.Face, error) {
 	
 	// Run face detection
 	angle := 0.0 // No rotation
-	detections := fd.classifier.RunCascade(cParams, angle)
+	detections := classifier.RunCascade(cParams, angle)
 	
 	// Cluster detections to remove duplicates
-	detections = fd.classifier.ClusterDetections(detections, float64(fd.config.IoUThreshold))
+	detections = classifier.ClusterDetections(detections, float64(cfg.IoUThreshold))
 	
 	// Filter detections by confidence threshold
 	var filteredDetections []pigo.Detection
 	for _, det := range detections {
-		if float32(det.Q) >= fd.config.MinConfidence {
+		if float32(det.Q) >= cfg.MinConfidence {
 			filteredDetections = append(filteredDetections, det)
 		}
 	}
@@ -90,16 +376,25 @@ func (fd *FaceDetector) DetectFaces(img image.Image) ([]models.Face, error) {
 			Confidence: det.Q,
 		}
 	}
-	
+
 	return faces, nil
 }
 
+// Selfie validation issue codes, one per failed check
+const (
+	SelfieIssueNoFace        = "NO_FACE"
+	SelfieIssueTooFewFaces   = "TOO_FEW_FACES"
+	SelfieIssueTooManyFaces  = "TOO_MANY_FACES"
+	SelfieIssueLowConfidence = "LOW_CONFIDENCE"
+)
 
-
-// ValidateSelfie validates if the image is a good selfie based on face count and quality
-func (fd *FaceDetector) ValidateSelfie(faces []models.Face, minFaces, maxFaces int) models.SelfieValidationResponse {
+// ValidateSelfie validates if the image is a good selfie based on face count and quality;
+// lowConfidence is the detector's threshold for unreliable detections. It records no
+// metrics, so offline tools can reuse it.
+func ValidateSelfie(faces []models.Face, minFaces, maxFaces int, lowConfidence float32) models.SelfieValidationResponse {
 	faceCount := len(faces)
 	issues := make([]string, 0)
+	var issueCodes []string
 	isValid := true
 	var confidence float32
 
@@ -109,12 +404,15 @@ func (fd *FaceDetector) ValidateSelfie(faces []models.Face, minFaces, maxFaces i
 		if faceCount == 0 {
 			issues = append(issues, "No faces detected in image")
 			issues = append(issues, "Image may be too dark or blurry")
+			issueCodes = append(issueCodes, SelfieIssueNoFace)
 		} else {
 			issues = append(issues, fmt.Sprintf("Too few faces detected (%d found, expected at least %d)", faceCount, minFaces))
+			issueCodes = append(issueCodes, SelfieIssueTooFewFaces)
 		}
 	} else if faceCount > maxFaces {
 		isValid = false
 		issues = append(issues, fmt.Sprintf("Multiple faces detected (%d found, expected %d)", faceCount, maxFaces))
+		issueCodes = append(issueCodes, SelfieIssueTooManyFaces)
 	}
 
 	// Calculate confidence (average of all face confidences)
@@ -126,15 +424,17 @@ func (fd *FaceDetector) ValidateSelfie(faces []models.Face, minFaces, maxFaces i
 		confidence = totalConfidence / float32(faceCount)
 
 		// Check confidence threshold
-		if confidence < 10.0 {
+		if confidence < lowConfidence {
 			isValid = false
 			issues = append(issues, "Low confidence score for detected face(s)")
+			issueCodes = append(issueCodes, SelfieIssueLowConfidence)
 		}
 	}
 
 	return models.SelfieValidationResponse{
 		IsValid:    isValid,
 		Issues:     issues,
+		IssueCodes: issueCodes,
 		Confidence: confidence,
 		FaceCount:  faceCount,
 	}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"image"
+	"sort"
+
+	"face-recognition-api/internal/models"
+)
+
+// FaceMatch pairs a face of one detection result with a face of another by index
+type FaceMatch struct {
+	A   int     `json:"a"`
+	B   int     `json:"b"`
+	IoU float64 `json:"iou"`
+}
+
+// IoU returns the intersection over union of two face boxes
+func IoU(a, b models.Face) float64 {
+	ra := image.Rect(a.X, a.Y, a.X+a.Width, a.Y+a.Height)
+	rb := image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+
+	inter := ra.Intersect(rb)
+	if inter.Empty() {
+		return 0
+	}
+	intersection := float64(inter.Dx() * inter.Dy())
+	union := float64(ra.Dx()*ra.Dy()+rb.Dx()*rb.Dy()) - intersection
+	return intersection / union
+}
+
+// MatchFaces pairs faces of a with faces of b whose boxes overlap by at least minIoU,
+// greedily taking the best overlapping pairs first. Each face is matched at most once.
+func MatchFaces(a, b []models.Face, minIoU float64) []FaceMatch {
+	var candidates []FaceMatch
+	for i := range a {
+		for j := range b {
+			if iou := IoU(a[i], b[j]); iou >= minIoU && iou > 0 {
+				candidates = append(candidates, FaceMatch{A: i, B: j, IoU: iou})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].IoU > candidates[j].IoU
+	})
+
+	usedA := make([]bool, len(a))
+	usedB := make([]bool, len(b))
+	matches := make([]FaceMatch, 0, min(len(a), len(b)))
+	for _, c := range candidates {
+		if usedA[c.A] || usedB[c.B] {
+			continue
+		}
+		usedA[c.A], usedB[c.B] = true, true
+		matches = append(matches, c)
+	}
+	return matches
+}
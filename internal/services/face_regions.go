@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"face-recognition-api/internal/models"
+)
+
+// AnonymizeModes lists the ways AnonymizeFaces can obscure a face
+var AnonymizeModes = []string{"pixelate", "blur"}
+
+// AnonymizeOptions defines how faces are obscured
+type AnonymizeOptions struct {
+	Mode string
+	// Strength is the pixelation block size or blur radius in pixels; zero scales it
+	// with each face
+	Strength int
+	// Margin grows each face box by this fraction of its size on every side
+	Margin float64
+}
+
+// FaceRegion returns the box of face grown by margin, a fraction of its size on every
+// side, and clipped to bounds
+func FaceRegion(face models.Face, margin float64, bounds image.Rectangle) image.Rectangle {
+	dx := int(float64(face.Width) * margin)
+	dy := int(float64(face.Height) * margin)
+	return image.Rect(face.X-dx, face.Y-dy, face.X+face.Width+dx, face.Y+face.Height+dy).Intersect(bounds)
+}
+
+// CropFace returns a copy of the region of img holding face, grown by margin. The
+// result's bounds start at the origin.
+func CropFace(img image.Image, face models.Face, margin float64) *image.RGBA {
+	region := FaceRegion(face, margin, img.Bounds())
+	crop := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(crop, crop.Bounds(), img, region.Min, draw.Src)
+	return crop
+}
+
+// AnonymizeFaces returns a copy of img with every face region pixelated or blurred
+func AnonymizeFaces(img image.Image, faces []models.Face, opts AnonymizeOptions) (*image.RGBA, error) {
+	var obscure func(rgba *image.RGBA, region image.Rectangle, strength int)
+	switch opts.Mode {
+	case "pixelate":
+		obscure = pixelate
+	case "blur":
+		obscure = boxBlur
+	default:
+		return nil, fmt.Errorf("unknown anonymize mode %q, want one of %q", opts.Mode, AnonymizeModes)
+	}
+
+	rgba := cloneRGBA(img)
+	for _, face := range faces {
+		region := FaceRegion(face, opts.Margin, rgba.Bounds())
+		if region.Empty() {
+			continue
+		}
+		strength := opts.Strength
+		if strength <= 0 {
+			strength = max(region.Dx(), region.Dy())/10 + 1
+		}
+		obscure(rgba, region, strength)
+	}
+	return rgba, nil
+}
+
+// pixelate fills each block of region with its average color
+func pixelate(img *image.RGBA, region image.Rectangle, block int) {
+	for by := region.Min.Y; by < region.Max.Y; by += block {
+		for bx := region.Min.X; bx < region.Max.X; bx += block {
+			cell := image.Rect(bx, by, bx+block, by+block).Intersect(region)
+
+			var sum [4]int
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				row := img.Pix[img.PixOffset(cell.Min.X, y):img.PixOffset(cell.Max.X, y)]
+				for i := 0; i < len(row); i++ {
+					sum[i%4] += int(row[i])
+				}
+			}
+
+			n := cell.Dx() * cell.Dy()
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				row := img.Pix[img.PixOffset(cell.Min.X, y):img.PixOffset(cell.Max.X, y)]
+				for i := 0; i < len(row); i++ {
+					row[i] = uint8(sum[i%4] / n)
+				}
+			}
+		}
+	}
+}
+
+// boxBlur blurs region with three passes of a separable box filter of the given radius,
+// which approximates a Gaussian blur. Samples are clamped to the region, so pixels outside
+// it neither change nor bleed in.
+func boxBlur(img *image.RGBA, region image.Rectangle, radius int) {
+	w, h := region.Dx(), region.Dy()
+	buf := make([]uint8, 4*w*h)
+	for y := 0; y < h; y++ {
+		copy(buf[4*w*y:4*w*(y+1)], img.Pix[img.PixOffset(region.Min.X, region.Min.Y+y):])
+	}
+
+	tmp := make([]uint8, len(buf))
+	for pass := 0; pass < 3; pass++ {
+		blurLines(tmp, buf, w, h, 4, 4*w, radius)
+		blurLines(buf, tmp, h, w, 4*w, 4, radius)
+	}
+
+	for y := 0; y < h; y++ {
+		copy(img.Pix[img.PixOffset(region.Min.X, region.Min.Y+y):], buf[4*w*y:4*w*(y+1)])
+	}
+}
+
+// blurLines box-filters lines of length n from src into dst, with a running sum per
+// channel. step is the byte distance between pixels of a line and stride between lines.
+func blurLines(dst, src []uint8, n, lines, step, stride, radius int) {
+	clamp := func(i int) int { return min(max(i, 0), n-1) }
+	window := 2*radius + 1
+
+	for line := 0; line < lines; line++ {
+		base := line * stride
+		for c := 0; c < 4; c++ {
+			sum := 0
+			for i := -radius; i <= radius; i++ {
+				sum += int(src[base+clamp(i)*step+c])
+			}
+			for i := 0; i < n; i++ {
+				dst[base+i*step+c] = uint8(sum / window)
+				sum += int(src[base+clamp(i+radius+1)*step+c]) - int(src[base+clamp(i-radius)*step+c])
+			}
+		}
+	}
+}
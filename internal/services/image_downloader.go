@@ -1,13 +1,18 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"image"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	// Import image decoders
@@ -15,24 +20,43 @@ import (
 	_ "image/png"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 
 	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/metrics"
 	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/requestid"
+	"face-recognition-api/internal/tracing"
+)
+
+// Image retrieval errors; returned errors wrap one of these so callers can classify failures
+var (
+	ErrInvalidURL        = errors.New("invalid URL")
+	ErrURLNotAllowed     = errors.New("URL not allowed")
+	ErrDownloadTimeout   = errors.New("image download timed out")
+	ErrImageNotFound     = errors.New("image not found")
+	ErrDownloadFailed    = errors.New("image download failed")
+	ErrImageTooLarge     = errors.New("image too large")
+	ErrImageDimensions   = errors.New("image dimensions too large")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrCorruptImage      = errors.New("corrupt image data")
 )
 
 // ImageDownloader handles downloading and validating images from URLs
 type ImageDownloader struct {
 	client *http.Client
-	config config.LimitsConfig
+	limits atomic.Pointer[config.LimitsConfig]
 	logger *logrus.Logger
 }
 
 // NewImageDownloader creates a new image downloader instance
 func NewImageDownloader(cfg config.LimitsConfig, logger *logrus.Logger) *ImageDownloader {
-	return &ImageDownloader{
+	id := &ImageDownloader{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
+			// Client spans time each fetch and carry the trace context to the image host
+			Transport: otelhttp.NewTransport(&http.Transport{
 				TLSClientConfig: &tls.Config{
 					InsecureSkipVerify: true, // Internal service - skip certificate validation
 				},
@@ -40,112 +64,205 @@ func NewImageDownloader(cfg config.LimitsConfig, logger *logrus.Logger) *ImageDo
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
 				DisableCompression:  false,
-			},
+			}),
 		},
-		config: cfg,
 		logger: logger,
 	}
+	id.SetLimits(cfg)
+	return id
+}
+
+// SetLimits replaces the size and dimension limits applied to new images
+func (id *ImageDownloader) SetLimits(cfg config.LimitsConfig) {
+	id.limits.Store(&cfg)
 }
 
 // DownloadImage downloads an image from the given URL and returns the decoded image
 func (id *ImageDownloader) DownloadImage(ctx context.Context, imageURL string) (image.Image, models.ImageMetadata, error) {
 	// Validate URL format
-	if err := id.validateURL(imageURL); err != nil {
-		return nil, models.ImageMetadata{}, fmt.Errorf("invalid URL: %w", err)
+	if err := ValidateURL(imageURL); err != nil {
+		return nil, models.ImageMetadata{}, err
+	}
+
+	start := time.Now()
+	data, err := id.fetch(ctx, imageURL)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.DownloadDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, models.ImageMetadata{}, err
+	}
+
+	img, metadata, err := id.decode(ctx, data, imageURL)
+	if err != nil {
+		return nil, models.ImageMetadata{}, err
 	}
 
+	id.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"url":        imageURL,
+		"width":      metadata.Width,
+		"height":     metadata.Height,
+		"format":     metadata.Format,
+		"size_bytes": metadata.SizeBytes,
+	}).Info("Image downloaded successfully")
+
+	return img, metadata, nil
+}
+
+// fetch requests imageURL and reads the response body within the size limit
+func (id *ImageDownloader) fetch(ctx context.Context, imageURL string) ([]byte, error) {
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
 	if err != nil {
-		return nil, models.ImageMetadata{}, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("User-Agent", "Face-Recognition-API/1.0")
 	req.Header.Set("Accept", "image/jpeg,image/png,image/*")
+	if requestID := requestid.FromContext(ctx); requestID != "" {
+		req.Header.Set(requestid.Header, requestID)
+	}
 
 	// Execute request
 	resp, err := id.client.Do(req)
 	if err != nil {
-		return nil, models.ImageMetadata{}, fmt.Errorf("failed to download image: %w", err)
+		return nil, downloadError(err)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, models.ImageMetadata{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%w: HTTP %d", ErrImageNotFound, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: HTTP %d", ErrDownloadFailed, resp.StatusCode)
 	}
 
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
 	if !id.isValidImageType(contentType) {
-		return nil, models.ImageMetadata{}, fmt.Errorf("unsupported content type: %s", contentType)
+		return nil, fmt.Errorf("%w: content type %q", ErrUnsupportedFormat, contentType)
 	}
 
 	// Check content length
-	if resp.ContentLength > id.config.MaxImageSize {
-		return nil, models.ImageMetadata{}, fmt.Errorf("image too large: %d bytes (max: %d)", resp.ContentLength, id.config.MaxImageSize)
+	if maxSize := id.limits.Load().MaxImageSize; resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrImageTooLarge, resp.ContentLength, maxSize)
+	}
+
+	data, err := id.ReadImage(resp.Body)
+	if err != nil && !errors.Is(err, ErrImageTooLarge) {
+		return nil, downloadError(err)
 	}
+	return data, err
+}
+
+// downloadError classifies a transport failure as a timeout or a generic download failure
+func downloadError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrDownloadTimeout, err)
+	}
+	return fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+}
+
+// DecodeImage decodes and validates an image supplied inline, e.g. as base64 or a multipart upload
+func (id *ImageDownloader) DecodeImage(ctx context.Context, data []byte) (image.Image, models.ImageMetadata, error) {
+	if maxSize := id.limits.Load().MaxImageSize; int64(len(data)) > maxSize {
+		return nil, models.ImageMetadata{}, fmt.Errorf("%w: %d bytes (max: %d)", ErrImageTooLarge, len(data), maxSize)
+	}
+
+	return id.decode(ctx, data, "")
+}
+
+// ReadImage reads at most MaxImageSize bytes from r and fails if the source holds more
+func (id *ImageDownloader) ReadImage(r io.Reader) ([]byte, error) {
+	maxSize := id.limits.Load().MaxImageSize
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrImageTooLarge, maxSize)
+	}
+
+	return data, nil
+}
 
-	// Decode image
-	img, format, err := image.Decode(resp.Body)
+// decode decodes image bytes and validates their dimensions
+func (id *ImageDownloader) decode(ctx context.Context, data []byte, imageURL string) (img image.Image, metadata models.ImageMetadata, err error) {
+	_, span := tracing.Start(ctx, "image.Decode", attribute.Int("image.size_bytes", len(data)))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	img, format, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return nil, models.ImageMetadata{}, fmt.Errorf("failed to decode image: %w", err)
+		metrics.DecodeDuration.WithLabelValues("invalid").Observe(time.Since(start).Seconds())
+		if errors.Is(err, image.ErrFormat) {
+			return nil, models.ImageMetadata{}, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+		}
+		return nil, models.ImageMetadata{}, fmt.Errorf("%w: %w", ErrCorruptImage, err)
 	}
+	// Only registered decoders can produce a format, so the label set stays bounded
+	metrics.DecodeDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
 
 	// Validate image dimensions
 	bounds := img.Bounds()
 	width, height := bounds.Dx(), bounds.Dy()
+	span.SetAttributes(
+		attribute.String("image.format", format),
+		attribute.Int("image.width", width),
+		attribute.Int("image.height", height),
+	)
 	
-	if width > id.config.MaxWidth || height > id.config.MaxHeight {
-		return nil, models.ImageMetadata{}, fmt.Errorf("image dimensions too large: %dx%d (max: %dx%d)", 
-			width, height, id.config.MaxWidth, id.config.MaxHeight)
+	if limits := id.limits.Load(); width > limits.MaxWidth || height > limits.MaxHeight {
+		return nil, models.ImageMetadata{}, fmt.Errorf("%w: %dx%d (max: %dx%d)", ErrImageDimensions,
+			width, height, limits.MaxWidth, limits.MaxHeight)
 	}
 
+	metrics.ImageSize.Observe(float64(len(data)))
+	metrics.ImageDimension.WithLabelValues("width").Observe(float64(width))
+	metrics.ImageDimension.WithLabelValues("height").Observe(float64(height))
+
 	// Create metadata
-	metadata := models.ImageMetadata{
+	metadata = models.ImageMetadata{
 		Width:     width,
 		Height:    height,
 		Format:    strings.ToUpper(format),
-		SizeBytes: resp.ContentLength,
+		SizeBytes: int64(len(data)),
 		URL:       imageURL,
 	}
 
-	id.logger.WithFields(logrus.Fields{
-		"url":         imageURL,
-		"width":       width,
-		"height":      height,
-		"format":      format,
-		"size_bytes":  resp.ContentLength,
-	}).Info("Image downloaded successfully")
-
 	return img, metadata, nil
 }
 
-// validateURL validates the image URL format and security
-func (id *ImageDownloader) validateURL(imageURL string) error {
-	if imageURL == "" {
-		return fmt.Errorf("URL cannot be empty")
+// ValidateURL validates the URL format and security of image and callback URLs
+func ValidateURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("%w: URL cannot be empty", ErrInvalidURL)
 	}
 
-	parsedURL, err := url.Parse(imageURL)
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("invalid URL format: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
 	}
 
 	// Check scheme
 	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
+		return fmt.Errorf("%w: unsupported URL scheme: %s", ErrInvalidURL, parsedURL.Scheme)
 	}
 
 	// Check host
 	if parsedURL.Host == "" {
-		return fmt.Errorf("missing host in URL")
+		return fmt.Errorf("%w: missing host in URL", ErrInvalidURL)
 	}
 
 	// Basic SSRF protection - block private IP ranges
-	if id.isPrivateIP(parsedURL.Host) {
-		return fmt.Errorf("access to private IP ranges is not allowed")
+	if isPrivateIP(parsedURL.Host) {
+		return fmt.Errorf("%w: access to private IP ranges is not allowed", ErrURLNotAllowed)
 	}
 
 	return nil
@@ -172,7 +289,7 @@ func (id *ImageDownloader) isValidImageType(contentType string) bool {
 }
 
 // isPrivateIP performs basic check for private IP ranges (simplified)
-func (id *ImageDownloader) isPrivateIP(host string) bool {
+func isPrivateIP(host string) bool {
 	// This is a simplified check - in production, you'd want more comprehensive validation
 	privateHosts := []string{
 		"localhost",
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"image"
 	"image/color"
@@ -21,7 +22,7 @@ type ImageProcessor struct {
 
 // CircleOptions defines options for drawing circles on images
 type CircleOptions struct {
-	Color     color.RGBA
+	Color     color.NRGBA
 	LineWidth int
 }
 
@@ -33,28 +34,14 @@ func NewImageProcessor(logger *logrus.Logger) *ImageProcessor {
 }
 
 // DrawFaceCircles draws circles around detected faces and returns base64 encoded image
-func (ip *ImageProcessor) DrawFaceCircles(img image.Image, faces []models.Face, opts CircleOptions) (string, error) {
-	// Create a new RGBA image from the original
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-
-	// Draw circles around detected faces
-	for _, face := range faces {
-		centerX := face.X + face.Width/2
-		centerY := face.Y + face.Height/2
-		radius := int(math.Max(float64(face.Width), float64(face.Height)) / 2)
-
-		ip.drawCircle(rgba, centerX, centerY, radius, opts.Color, opts.LineWidth)
-	}
-
+func (ip *ImageProcessor) DrawFaceCircles(ctx context.Context, img image.Image, faces []models.Face, opts CircleOptions) (string, error) {
 	// Encode to base64
-	encoded, err := ip.encodeToBase64(rgba)
+	encoded, err := ip.encodeToBase64(AnnotateFaces(img, faces, opts))
 	if err != nil {
 		return "", err
 	}
 
-	ip.logger.WithFields(logrus.Fields{
+	ip.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"faces_processed": len(faces),
 		"circle_color":    opts.Color,
 		"line_width":      opts.LineWidth,
@@ -63,47 +50,25 @@ func (ip *ImageProcessor) DrawFaceCircles(img image.Image, faces []models.Face,
 	return encoded, nil
 }
 
-// drawCircle draws a circle using Bresenham's circle algorithm with line width
-func (ip *ImageProcessor) drawCircle(img *image.RGBA, centerX, centerY, radius int, col color.RGBA, lineWidth int) {
-	// Bresenham's circle algorithm with line width
-	for w := 0; w < lineWidth; w++ {
-		r := radius + w - lineWidth/2
-		if r <= 0 {
-			continue
-		}
-
-		x := 0
-		y := r
-		d := 3 - 2*r
-
-		for x <= y {
-			// Draw 8 symmetric points
-			ip.setPixelSafe(img, centerX+x, centerY+y, col)
-			ip.setPixelSafe(img, centerX-x, centerY+y, col)
-			ip.setPixelSafe(img, centerX+x, centerY-y, col)
-			ip.setPixelSafe(img, centerX-x, centerY-y, col)
-			ip.setPixelSafe(img, centerX+y, centerY+x, col)
-			ip.setPixelSafe(img, centerX-y, centerY+x, col)
-			ip.setPixelSafe(img, centerX+y, centerY-x, col)
-			ip.setPixelSafe(img, centerX-y, centerY-x, col)
+// AnnotateFaces returns a copy of img with circles drawn around detected faces
+func AnnotateFaces(img image.Image, faces []models.Face, opts CircleOptions) *image.RGBA {
+	rgba := cloneRGBA(img)
+	for _, face := range faces {
+		centerX := float64(face.X) + float64(face.Width)/2
+		centerY := float64(face.Y) + float64(face.Height)/2
+		radius := math.Max(float64(face.Width), float64(face.Height)) / 2
 
-			if d < 0 {
-				d = d + 4*x + 6
-			} else {
-				d = d + 4*(x-y) + 10
-				y--
-			}
-			x++
-		}
+		strokeCircle(rgba, centerX, centerY, radius, float64(opts.LineWidth), opts.Color)
 	}
+	return rgba
 }
 
-// setPixelSafe safely sets a pixel within image bounds
-func (ip *ImageProcessor) setPixelSafe(img *image.RGBA, x, y int, col color.RGBA) {
+// cloneRGBA copies img into a new RGBA image with the same bounds
+func cloneRGBA(img image.Image) *image.RGBA {
 	bounds := img.Bounds()
-	if x >= bounds.Min.X && x < bounds.Max.X && y >= bounds.Min.Y && y < bounds.Max.Y {
-		img.Set(x, y, col)
-	}
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	return rgba
 }
 
 // encodeToBase64 encodes an image to base64 with data URL prefix
@@ -120,31 +85,3 @@ func (ip *ImageProcessor) encodeToBase64(img image.Image) (string, error) {
 	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
 	return "data:image/jpeg;base64," + encoded, nil
 }
-
-// ParseColor converts color name to RGBA color
-func (ip *ImageProcessor) ParseColor(colorName string) color.RGBA {
-	switch colorName {
-	case "red":
-		return color.RGBA{255, 0, 0, 255}
-	case "green":
-		return color.RGBA{0, 255, 0, 255}
-	case "blue":
-		return color.RGBA{0, 0, 255, 255}
-	case "yellow":
-		return color.RGBA{255, 255, 0, 255}
-	case "white":
-		return color.RGBA{255, 255, 255, 255}
-	case "black":
-		return color.RGBA{0, 0, 0, 255}
-	case "orange":
-		return color.RGBA{255, 165, 0, 255}
-	case "purple":
-		return color.RGBA{128, 0, 128, 255}
-	case "pink":
-		return color.RGBA{255, 192, 203, 255}
-	case "cyan":
-		return color.RGBA{0, 255, 255, 255}
-	default:
-		return color.RGBA{255, 0, 0, 255} // Default to red
-	}
-}
\ No newline at end of file
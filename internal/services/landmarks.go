@@ -0,0 +1,167 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/esimov/pigo/core"
+
+	"face-recognition-api/internal/models"
+)
+
+// Landmark cascades from the pigo repository (MIT license): pupil localization, and the
+// facial landmark point cascades for the nose tip, mouth corner and lips
+var (
+	//go:embed landmarks/puploc
+	puplocCascadeFile []byte
+	//go:embed landmarks/lp93
+	noseCascadeFile []byte
+	//go:embed landmarks/lp84
+	mouthCornerCascadeFile []byte
+	//go:embed landmarks/lp81
+	upperLipCascadeFile []byte
+	//go:embed landmarks/lp82
+	lowerLipCascadeFile []byte
+)
+
+// landmarkPerturbs is the number of randomly perturbed runs each localization takes the
+// median of; pigo's result buffers are sized for exactly this many
+const landmarkPerturbs = 63
+
+// landmarkCascades are the unpacked localization cascades, shared by every request
+type landmarkCascades struct {
+	pupil, nose, mouthCorner, upperLip, lowerLip *pigo.PuplocCascade
+}
+
+// loadLandmarkCascades unpacks the embedded cascades once, on first use
+var loadLandmarkCascades = sync.OnceValues(func() (*landmarkCascades, error) {
+	unpack := func(name string, data []byte) (*pigo.PuplocCascade, error) {
+		cascade, err := pigo.NewPuplocCascade().UnpackCascade(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack %s cascade: %w", name, err)
+		}
+		return cascade, nil
+	}
+
+	var lc landmarkCascades
+	var err error
+	if lc.pupil, err = unpack("pupil", puplocCascadeFile); err != nil {
+		return nil, err
+	}
+	if lc.nose, err = unpack("nose", noseCascadeFile); err != nil {
+		return nil, err
+	}
+	if lc.mouthCorner, err = unpack("mouth corner", mouthCornerCascadeFile); err != nil {
+		return nil, err
+	}
+	if lc.upperLip, err = unpack("upper lip", upperLipCascadeFile); err != nil {
+		return nil, err
+	}
+	if lc.lowerLip, err = unpack("lower lip", lowerLipCascadeFile); err != nil {
+		return nil, err
+	}
+	return &lc, nil
+})
+
+// landmarkMargin is the fraction of a face's size around it that landmark localization
+// samples, covering the perturbed search windows of every cascade
+const landmarkMargin = 0.5
+
+// LocateLandmarks returns a copy of faces with the pupils, nose tip, mouth corners and lips
+// located inside each face. Points a cascade can't place inside the image are left out,
+// and the nose, mouth and lip points are only located when both pupils are found. Only
+// the region around each face is converted to grayscale.
+func LocateLandmarks(img image.Image, faces []models.Face) ([]models.Face, error) {
+	cascades, err := loadLandmarkCascades()
+	if err != nil {
+		return nil, err
+	}
+
+	located := make([]models.Face, len(faces))
+	for i, face := range faces {
+		located[i] = face
+		region := FaceRegion(face, landmarkMargin, img.Bounds())
+		if region.Empty() {
+			continue
+		}
+		params := pigo.ImageParams{
+			Pixels: grayscaleRegion(img, region),
+			Rows:   region.Dy(),
+			Cols:   region.Dx(),
+			Dim:    region.Dx(),
+		}
+		located[i].Landmarks = cascades.locate(params, face, region.Min)
+	}
+	return located, nil
+}
+
+// grayscaleRegion converts region of img to grayscale the way pigo.RgbToGrayscale converts
+// a whole image, row by row from region.Min
+func grayscaleRegion(img image.Image, region image.Rectangle) []uint8 {
+	width := region.Dx()
+	gray := make([]uint8, width*region.Dy())
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		row := gray[(y-region.Min.Y)*width:]
+		for x := region.Min.X; x < region.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			row[x-region.Min.X] = uint8((0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 256)
+		}
+	}
+	return gray
+}
+
+// locate finds the landmarks of one face in params, a grayscale region of the image
+// starting at origin, seeding the pupil search from the face box the way pigo's own
+// tooling does
+func (lc *landmarkCascades) locate(params pigo.ImageParams, face models.Face, origin image.Point) []models.Landmark {
+	scale := float32(face.Width)
+	row := face.Y + face.Height/2 - origin.Y
+	col := face.X + face.Width/2 - origin.X
+
+	seed := func(offset float32) pigo.Puploc {
+		return pigo.Puploc{
+			Row:      row - int(0.075*scale),
+			Col:      col + int(offset*scale),
+			Scale:    scale * 0.25,
+			Perturbs: landmarkPerturbs,
+		}
+	}
+	leftEye := lc.pupil.RunDetector(seed(-0.175), params, 0, false)
+	rightEye := lc.pupil.RunDetector(seed(0.175), params, 0, false)
+
+	var landmarks []models.Landmark
+	add := func(name string, p *pigo.Puploc) bool {
+		if p.Row <= 0 || p.Col <= 0 || p.Row >= params.Rows || p.Col >= params.Cols {
+			return false
+		}
+		landmarks = append(landmarks, models.Landmark{Name: name, X: origin.X + p.Col, Y: origin.Y + p.Row})
+		return true
+	}
+
+	leftFound := add("left_pupil", leftEye)
+	rightFound := add("right_pupil", rightEye)
+	if !leftFound || !rightFound {
+		return landmarks
+	}
+
+	add("nose_tip", lc.nose.GetLandmarkPoint(leftEye, rightEye, params, landmarkPerturbs, false))
+
+	// The corner cascade finds one corner unflipped and the other flipped; name them by
+	// where they land rather than by which run found them
+	corners := []*pigo.Puploc{
+		lc.mouthCorner.GetLandmarkPoint(leftEye, rightEye, params, landmarkPerturbs, false),
+		lc.mouthCorner.GetLandmarkPoint(leftEye, rightEye, params, landmarkPerturbs, true),
+	}
+	if corners[0].Col > corners[1].Col {
+		corners[0], corners[1] = corners[1], corners[0]
+	}
+	add("left_mouth_corner", corners[0])
+	add("right_mouth_corner", corners[1])
+
+	add("upper_lip", lc.upperLip.GetLandmarkPoint(leftEye, rightEye, params, landmarkPerturbs, false))
+	add("lower_lip", lc.lowerLip.GetLandmarkPoint(leftEye, rightEye, params, landmarkPerturbs, false))
+
+	return landmarks
+}
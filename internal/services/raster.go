@@ -0,0 +1,129 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ring describes an anti-aliased circular stroke in pixel coordinates
+type ring struct {
+	cx, cy      float64
+	radius      float64
+	half        float64
+	maxCoverage float64
+	// Squared radii between which pixels are fully covered
+	solidIn2, solidOut2 float64
+}
+
+// strokeCircle renders an anti-aliased ring of the given stroke width centered on radius.
+// Coverage is computed analytically from each pixel center's distance to the ring, so thick
+// strokes are solid and free of the gaps left by stacking 1px rings. Only the rows and spans
+// that intersect the ring are visited and pixels are blended directly into img.Pix.
+func strokeCircle(img *image.RGBA, cx, cy, radius, width float64, col color.NRGBA) {
+	if col.A == 0 || width <= 0 || radius <= 0 {
+		return
+	}
+
+	half := width / 2
+	// Coverage falls to zero half a pixel beyond each edge of the stroke
+	outer := radius + half + 0.5
+	inner := radius - half - 0.5
+	outer2 := outer * outer
+	inner2 := inner * inner
+	if inner < 0 {
+		inner2 = 0
+	}
+
+	r := ring{
+		cx:     cx,
+		cy:     cy,
+		radius: radius,
+		half:   half,
+		// Thin strokes never reach full coverage
+		maxCoverage: math.Min(width, 1),
+		solidIn2:    math.Inf(1),
+		solidOut2:   math.Inf(-1),
+	}
+	if width >= 1 {
+		solidIn := math.Max(radius-half+0.5, 0)
+		solidOut := radius + half - 0.5
+		r.solidIn2, r.solidOut2 = solidIn*solidIn, solidOut*solidOut
+	}
+
+	bounds := img.Bounds()
+	y0 := max(bounds.Min.Y, int(math.Floor(cy-outer)))
+	y1 := min(bounds.Max.Y-1, int(math.Ceil(cy+outer)))
+
+	for y := y0; y <= y1; y++ {
+		dy := float64(y) + 0.5 - cy
+		dy2 := dy * dy
+		if dy2 >= outer2 {
+			continue
+		}
+
+		ox := math.Sqrt(outer2 - dy2)
+		row := img.Pix[img.PixOffset(bounds.Min.X, y):]
+		x0 := max(bounds.Min.X, int(math.Floor(cx-ox)))
+		x1 := min(bounds.Max.X-1, int(math.Ceil(cx+ox)))
+
+		// Rows crossing the hollow middle are split into a left and a right span
+		if inner > 0 && dy2 < inner2 {
+			ix := math.Sqrt(inner2 - dy2)
+			left := int(math.Ceil(cx - ix))
+			right := int(math.Floor(cx + ix))
+			if left < right {
+				r.span(row, x0-bounds.Min.X, min(x1, left)-bounds.Min.X, bounds.Min.X, dy2, col)
+				r.span(row, max(x0, right)-bounds.Min.X, x1-bounds.Min.X, bounds.Min.X, dy2, col)
+				continue
+			}
+		}
+		r.span(row, x0-bounds.Min.X, x1-bounds.Min.X, bounds.Min.X, dy2, col)
+	}
+}
+
+// span blends the ring pixels of one row between row offsets i0 and i1 inclusive.
+// Pixels inside the solid band skip the square root and anti-aliasing math.
+func (r *ring) span(row []uint8, i0, i1, minX int, dy2 float64, col color.NRGBA) {
+	if i0 > i1 {
+		return
+	}
+	row = row[i0*4 : i1*4+4]
+	solidAlpha := uint32(col.A)
+	dx := float64(i0+minX) + 0.5 - r.cx
+
+	for j := 0; j+3 < len(row); j, dx = j+4, dx+1 {
+		d2 := dx*dx + dy2
+
+		alpha := solidAlpha
+		if d2 < r.solidIn2 || d2 > r.solidOut2 {
+			coverage := r.half + 0.5 - math.Abs(math.Sqrt(d2)-r.radius)
+			if coverage <= 0 {
+				continue
+			}
+			if coverage > r.maxCoverage {
+				coverage = r.maxCoverage
+			}
+			alpha = uint32(float64(col.A)*coverage + 0.5)
+		}
+
+		p := row[j : j+4 : j+4]
+		if alpha >= 0xff {
+			p[0], p[1], p[2], p[3] = col.R, col.G, col.B, 0xff
+			continue
+		}
+
+		// Source-over onto a premultiplied pixel: dst = src*alpha + dst*(1-alpha)
+		inv := 0xff - alpha
+		p[0] = div255(uint32(col.R)*alpha + uint32(p[0])*inv)
+		p[1] = div255(uint32(col.G)*alpha + uint32(p[1])*inv)
+		p[2] = div255(uint32(col.B)*alpha + uint32(p[2])*inv)
+		p[3] = uint8(alpha) + div255(uint32(p[3])*inv)
+	}
+}
+
+// div255 divides v by 255 with rounding, avoiding an integer division
+func div255(v uint32) uint8 {
+	v += 0x80
+	return uint8((v + v>>8) >> 8)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"face-recognition-api/internal/models"
+)
+
+// benchmarkLineWidths covers thin and thick strokes
+var benchmarkLineWidths = []int{1, 3, 12}
+
+// benchmarkFaces returns n faces of 80 to 374 pixels spread over a size x size image, the
+// same for every run
+func benchmarkFaces(size, n int) []models.Face {
+	rng := rand.New(rand.NewSource(1))
+	faces := make([]models.Face, n)
+	for i := range faces {
+		side := 80 + rng.Intn(295)
+		faces[i] = models.Face{X: rng.Intn(size - side), Y: rng.Intn(size - side), Width: side, Height: side}
+	}
+	return faces
+}
+
+// BenchmarkAnnotateFaces measures annotating a large image with many faces, copying
+// the image included
+func BenchmarkAnnotateFaces(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 2000))
+	faces := benchmarkFaces(2000, 50)
+
+	for _, width := range benchmarkLineWidths {
+		b.Run(fmt.Sprintf("width=%d", width), func(b *testing.B) {
+			opts := CircleOptions{Color: color.NRGBA{R: 255, A: 255}, LineWidth: width}
+			for i := 0; i < b.N; i++ {
+				AnnotateFaces(img, faces, opts)
+			}
+		})
+	}
+}
+
+// BenchmarkStrokeCircles measures the analytic ring rasterizer alone, for comparison with
+// BenchmarkBresenhamCircles
+func BenchmarkStrokeCircles(b *testing.B) {
+	benchmarkCircles(b, func(img *image.RGBA, cx, cy, radius float64, width int, col color.NRGBA) {
+		strokeCircle(img, cx, cy, radius, float64(width), col)
+	})
+}
+
+// BenchmarkBresenhamCircles measures the stacked 1px Bresenham rings strokeCircle
+// replaced, drawn through image.RGBA's per-pixel accessors
+func BenchmarkBresenhamCircles(b *testing.B) {
+	benchmarkCircles(b, func(img *image.RGBA, cx, cy, radius float64, width int, col color.NRGBA) {
+		bresenhamCircle(img, int(cx), int(cy), int(radius), col, width)
+	})
+}
+
+// benchmarkCircles draws circles around many faces on a large image with draw, for every
+// line width in benchmarkLineWidths
+func benchmarkCircles(b *testing.B, draw func(img *image.RGBA, cx, cy, radius float64, width int, col color.NRGBA)) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 2000))
+	faces := benchmarkFaces(2000, 50)
+
+	for _, width := range benchmarkLineWidths {
+		for _, col := range []color.NRGBA{{R: 255, A: 255}, {R: 255, A: 128}} {
+			b.Run(fmt.Sprintf("width=%d/alpha=%d", width, col.A), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for _, face := range faces {
+						cx := float64(face.X) + float64(face.Width)/2
+						cy := float64(face.Y) + float64(face.Height)/2
+						radius := math.Max(float64(face.Width), float64(face.Height)) / 2
+						draw(img, cx, cy, radius, width, col)
+					}
+				}
+			})
+		}
+	}
+}
+
+// bresenhamCircle is the previous annotation renderer, kept as a benchmark baseline: one
+// Bresenham circle per pixel of line width
+func bresenhamCircle(img *image.RGBA, centerX, centerY, radius int, col color.NRGBA, lineWidth int) {
+	for w := 0; w < lineWidth; w++ {
+		r := radius + w - lineWidth/2
+		if r <= 0 {
+			continue
+		}
+
+		x, y := 0, r
+		d := 3 - 2*r
+		for x <= y {
+			for _, p := range [8][2]int{{x, y}, {-x, y}, {x, -y}, {-x, -y}, {y, x}, {-y, x}, {y, -x}, {-y, -x}} {
+				blendPixel(img, centerX+p[0], centerY+p[1], col)
+			}
+			if d < 0 {
+				d += 4*x + 6
+			} else {
+				d += 4*(x-y) + 10
+				y--
+			}
+			x++
+		}
+	}
+}
+
+// blendPixel composites col over one pixel with source-over, ignoring pixels outside img
+func blendPixel(img *image.RGBA, x, y int, col color.NRGBA) {
+	if !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+		return
+	}
+	if col.A == 0xff {
+		img.SetRGBA(x, y, color.RGBA{col.R, col.G, col.B, 0xff})
+		return
+	}
+
+	dst := img.RGBAAt(x, y)
+	a := uint32(col.A)
+	inv := 0xff - a
+	img.SetRGBA(x, y, color.RGBA{
+		R: uint8((uint32(col.R)*a + uint32(dst.R)*inv) / 0xff),
+		G: uint8((uint32(col.G)*a + uint32(dst.G)*inv) / 0xff),
+		B: uint8((uint32(col.B)*a + uint32(dst.B)*inv) / 0xff),
+		A: uint8(a + uint32(dst.A)*inv/0xff),
+	})
+}
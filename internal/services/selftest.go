@@ -0,0 +1,47 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"image/jpeg"
+	"time"
+)
+
+// selfTestImage is a single-face portrait, downscaled from pigo's test sample (MIT license)
+//
+//go:embed selftest.jpg
+var selfTestImage []byte
+
+// selfTestFaces is the number of faces in selfTestImage, which every backend must find with
+// its default configuration
+const selfTestFaces = 1
+
+// SelfTest runs detection on an embedded reference portrait and fails when the face count
+// differs from the known one or detection takes longer than budget. Detection can't be
+// interrupted, so ctx is only checked before it starts.
+func SelfTest(ctx context.Context, detector Detector, budget time.Duration) error {
+	img, err := jpeg.Decode(bytes.NewReader(selfTestImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode reference image: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	faces, err := detector.DetectFaces(img)
+	if err != nil {
+		return err
+	}
+	latency := time.Since(start)
+
+	if len(faces) != selfTestFaces {
+		return fmt.Errorf("detected %d faces in reference image, expected %d", len(faces), selfTestFaces)
+	}
+	if budget > 0 && latency > budget {
+		return fmt.Errorf("reference detection took %s, budget is %s", latency.Round(time.Millisecond), budget)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/metrics"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/requestid"
+)
+
+// shadowSample is a primary detection waiting to be repeated with the shadow detector
+type shadowSample struct {
+	requestID string
+	img       image.Image
+	primary   models.DetectorInfo
+	faces     []models.Face
+}
+
+// shadowResult is the detector and faces of one side of a comparison
+type shadowResult struct {
+	Detector models.DetectorInfo `json:"detector"`
+	Faces    []models.Face       `json:"faces"`
+}
+
+// shadowRecord is the sample log entry written for each comparison
+type shadowRecord struct {
+	Time      time.Time    `json:"time"`
+	RequestID string       `json:"request_id,omitempty"`
+	Agree     bool         `json:"agree"`
+	Primary   shadowResult `json:"primary"`
+	Shadow    shadowResult `json:"shadow"`
+	// Matches pair primary faces (a) with shadow faces (b) by index
+	Matches    []FaceMatch `json:"matches"`
+	DurationMs float64     `json:"shadow_duration_ms"`
+}
+
+// shadowDeferredKey is the context key of the samples held until the response is sent
+type shadowDeferredKey struct{}
+
+// shadowDeferred collects the samples taken while serving one request
+type shadowDeferred struct {
+	mu      sync.Mutex
+	samples []*shadowSample
+}
+
+// ShadowRunner repeats a sampled fraction of live detections with a shadow detector on its
+// own workers and records how the two results agree, so a retuned configuration can be
+// compared against live traffic before it serves any
+type ShadowRunner struct {
+	detector Detector
+	config   config.ShadowConfig
+	logger   *logrus.Logger
+
+	mu      sync.Mutex
+	queue   chan *shadowSample
+	stopped bool
+
+	// abandon makes workers skip what is still queued once shutdown runs out of time
+	abandon atomic.Bool
+
+	sampleLogMu sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// NewShadowRunner creates a new shadow runner instance comparing against detector
+func NewShadowRunner(cfg config.ShadowConfig, detector Detector, logger *logrus.Logger) *ShadowRunner {
+	return &ShadowRunner{
+		detector: detector,
+		config:   cfg,
+		logger:   logger,
+		queue:    make(chan *shadowSample, max(cfg.QueueSize, 1)),
+	}
+}
+
+// Detector returns the shadow detector
+func (s *ShadowRunner) Detector() Detector {
+	return s.detector
+}
+
+// Start starts the comparison workers
+func (s *ShadowRunner) Start() {
+	workers := max(s.config.Workers, 1)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+}
+
+// Stop stops accepting samples and waits for the queued comparisons until ctx is done.
+// Comparisons still queued at that point are skipped, and the returned error reports how
+// many there were.
+func (s *ShadowRunner) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		abandoned := len(s.queue)
+		s.abandon.Store(true)
+		<-done
+		return fmt.Errorf("%d shadow comparisons abandoned: %w", abandoned, ctx.Err())
+	}
+}
+
+// Middleware returns middleware that holds the samples taken while serving a request until
+// the response is sent, so shadow detection never delays it
+func (s *ShadowRunner) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deferred := &shadowDeferred{}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), shadowDeferredKey{}, deferred)))
+
+			deferred.mu.Lock()
+			samples := deferred.samples
+			deferred.samples = nil
+			deferred.mu.Unlock()
+			if len(samples) == 0 {
+				return
+			}
+
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			for _, sample := range samples {
+				s.submit(sample)
+			}
+		})
+	}
+}
+
+// Observe samples a primary detection of img for comparison with the shadow detector.
+// Samples taken while serving a request are held until its response is sent, up to the
+// queue size; the rest are queued right away.
+func (s *ShadowRunner) Observe(ctx context.Context, primary Detector, img image.Image, faces []models.Face) {
+	if rand.Float64() >= s.config.SampleRate {
+		return
+	}
+
+	sample := &shadowSample{
+		requestID: requestid.FromContext(ctx),
+		img:       img,
+		primary:   models.DetectorInfo{Name: primary.Name(), Version: primary.Version()},
+		faces:     faces,
+	}
+
+	if deferred, ok := ctx.Value(shadowDeferredKey{}).(*shadowDeferred); ok {
+		deferred.mu.Lock()
+		held := len(deferred.samples) < cap(s.queue)
+		if held {
+			deferred.samples = append(deferred.samples, sample)
+		}
+		deferred.mu.Unlock()
+		if held {
+			return
+		}
+	}
+	s.submit(sample)
+}
+
+// submit queues a sample, dropping it when the queue is full or the runner is stopped
+func (s *ShadowRunner) submit(sample *shadowSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		select {
+		case s.queue <- sample:
+			return
+		default:
+		}
+	}
+	metrics.ShadowComparisons.WithLabelValues("dropped").Inc()
+}
+
+// worker compares queued samples until the queue is closed
+func (s *ShadowRunner) worker() {
+	defer s.wg.Done()
+
+	for sample := range s.queue {
+		if s.abandon.Load() {
+			continue
+		}
+		s.compare(sample)
+	}
+}
+
+// compare runs the shadow detector on a sample and records how its result differs from
+// the primary one
+func (s *ShadowRunner) compare(sample *shadowSample) {
+	start := time.Now()
+	faces, err := s.detector.DetectFaces(sample.img)
+	duration := time.Since(start)
+	metrics.ShadowDetectionDuration.Observe(duration.Seconds())
+
+	if err != nil {
+		metrics.ShadowComparisons.WithLabelValues("error").Inc()
+		s.log(sample).WithError(err).Warn("Shadow detection failed")
+		return
+	}
+
+	matches := MatchFaces(sample.faces, faces, s.config.MatchIoU)
+	unmatchedPrimary := len(sample.faces) - len(matches)
+	unmatchedShadow := len(faces) - len(matches)
+	agree := unmatchedPrimary == 0 && unmatchedShadow == 0
+
+	outcome := "disagree"
+	if agree {
+		outcome = "agree"
+	}
+	metrics.ShadowComparisons.WithLabelValues(outcome).Inc()
+	metrics.ShadowFaceCountDelta.Observe(float64(len(faces) - len(sample.faces)))
+	metrics.ShadowUnmatchedFaces.WithLabelValues("primary").Add(float64(unmatchedPrimary))
+	metrics.ShadowUnmatchedFaces.WithLabelValues("shadow").Add(float64(unmatchedShadow))
+	for _, m := range matches {
+		metrics.ShadowMatchIoU.Observe(m.IoU)
+		metrics.ShadowConfidenceDelta.Observe(float64(faces[m.B].Confidence - sample.faces[m.A].Confidence))
+	}
+
+	s.log(sample).WithFields(logrus.Fields{
+		"primary_faces": len(sample.faces),
+		"shadow_faces":  len(faces),
+		"matched":       len(matches),
+		"agree":         agree,
+	}).Debug("Shadow detection compared")
+
+	if s.config.SampleLog != "" {
+		s.writeSample(sample, shadowRecord{
+			Time:      time.Now().UTC(),
+			RequestID: sample.requestID,
+			Agree:     agree,
+			Primary:   shadowResult{Detector: sample.primary, Faces: sample.faces},
+			Shadow: shadowResult{
+				Detector: models.DetectorInfo{Name: s.detector.Name(), Version: s.detector.Version()},
+				Faces:    faces,
+			},
+			Matches:    matches,
+			DurationMs: duration.Seconds() * 1000,
+		})
+	}
+}
+
+// writeSample appends a comparison to the sample log
+func (s *ShadowRunner) writeSample(sample *shadowSample, record shadowRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		s.log(sample).WithError(err).Error("Failed to encode shadow sample")
+		return
+	}
+
+	s.sampleLogMu.Lock()
+	defer s.sampleLogMu.Unlock()
+
+	f, err := os.OpenFile(s.config.SampleLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		s.log(sample).WithError(err).Error("Failed to open shadow sample log")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		s.log(sample).WithError(err).Error("Failed to write shadow sample log")
+	}
+}
+
+// log returns a log entry tagged with the request ID the sample was taken from
+func (s *ShadowRunner) log(sample *shadowSample) *logrus.Entry {
+	return s.logger.WithContext(requestid.NewContext(context.Background(), sample.requestID))
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync/atomic"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/models"
+)
+
+// skinVersion identifies the revision of the skin detection algorithm
+const skinVersion = "1.0.0"
+
+// skinWorkSize bounds the longer side of the downscaled image the skin detector works on
+const skinWorkSize = 320
+
+// SkinDetector is a pure-Go detection backend that needs no model data. It finds regions
+// of skin-colored pixels with the proportions of a face and scores them with Haar-like
+// features: the eye band darker than the cheeks, the eyes darker than the bridge of the
+// nose and the mouth darker than the skin around it. Confidence is between 0 and 1.
+type SkinDetector struct {
+	config atomic.Pointer[config.SkinConfig]
+}
+
+// NewSkinDetector creates a new skin-color detector instance
+func NewSkinDetector(cfg config.SkinConfig) *SkinDetector {
+	d := &SkinDetector{}
+	d.config.Store(&cfg)
+	return d
+}
+
+// Name returns the backend name, skin
+func (d *SkinDetector) Name() string {
+	return "skin"
+}
+
+// Version returns the algorithm revision
+func (d *SkinDetector) Version() string {
+	return skinVersion
+}
+
+// Capabilities describes the skin backend. It relies on color, so grayscale images never
+// contain faces.
+func (d *SkinDetector) Capabilities() DetectorCapabilities {
+	return DetectorCapabilities{
+		MinFaceSize:   d.Config().MinSize,
+		MaxConfidence: 1,
+		LowConfidence: 0.5,
+	}
+}
+
+// Config returns the current detection parameters
+func (d *SkinDetector) Config() config.SkinConfig {
+	return *d.config.Load()
+}
+
+// SetConfig replaces the detection parameters; detections already running keep the old ones
+func (d *SkinDetector) SetConfig(cfg config.SkinConfig) {
+	d.config.Store(&cfg)
+}
+
+// skinRegion is the bounding box and pixel count of a connected group of skin pixels
+type skinRegion struct {
+	minX, minY, maxX, maxY int
+	pixels                 int
+}
+
+// DetectFaces detects faces in the given image and returns face coordinates
+func (d *SkinDetector) DetectFaces(img image.Image) ([]models.Face, error) {
+	cfg := d.Config()
+	plane := newSkinPlane(img)
+
+	minSide := float64(cfg.MinSize) / plane.scale
+	faces := make([]models.Face, 0)
+	for _, region := range plane.regions() {
+		width := region.maxX - region.minX + 1
+		height := region.maxY - region.minY + 1
+		if float64(width) < minSide || height < width*4/5 {
+			continue
+		}
+
+		// Skin below the chin belongs to the neck; a face is about 1.3 times as tall as wide
+		height = min(height, width*13/10)
+		box := image.Rect(region.minX, region.minY, region.minX+width, region.minY+height)
+		if plane.skinFraction(box) < 0.5 {
+			continue
+		}
+
+		confidence := plane.faceScore(box)
+		if confidence < cfg.MinConfidence {
+			continue
+		}
+
+		faces = append(faces, models.Face{
+			X:          plane.bounds.Min.X + int(float64(box.Min.X)*plane.scale),
+			Y:          plane.bounds.Min.Y + int(float64(box.Min.Y)*plane.scale),
+			Width:      int(math.Round(float64(box.Dx()) * plane.scale)),
+			Height:     int(math.Round(float64(box.Dy()) * plane.scale)),
+			Confidence: confidence,
+		})
+	}
+
+	return faces, nil
+}
+
+// skinPlane is a downscaled image as a skin mask plus integral images of the mask and of
+// the luma, so the sums over any rectangle cost four lookups
+type skinPlane struct {
+	bounds     image.Rectangle
+	scale      float64
+	cols, rows int
+	mask       []bool
+	maskSum    []int64
+	lumaSum    []int64
+}
+
+// newSkinPlane samples img onto a grid of at most skinWorkSize pixels per side
+func newSkinPlane(img image.Image) *skinPlane {
+	bounds := img.Bounds()
+	p := &skinPlane{bounds: bounds, scale: 1}
+	if longest := max(bounds.Dx(), bounds.Dy()); longest > skinWorkSize {
+		p.scale = float64(longest) / skinWorkSize
+	}
+	p.cols = max(int(float64(bounds.Dx())/p.scale), 1)
+	p.rows = max(int(float64(bounds.Dy())/p.scale), 1)
+
+	raw := make([]bool, p.cols*p.rows)
+	luma := make([]uint8, p.cols*p.rows)
+	for y := 0; y < p.rows; y++ {
+		sy := bounds.Min.Y + int(float64(y)*p.scale)
+		for x := 0; x < p.cols; x++ {
+			sx := bounds.Min.X + int(float64(x)*p.scale)
+			r, g, b, _ := img.At(sx, sy).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			luma[y*p.cols+x] = yy
+			raw[y*p.cols+x] = isSkin(yy, cb, cr)
+		}
+	}
+
+	// Smooth the mask with a 3x3 majority vote to close pores and drop speckles
+	rawSum := integrate(p.cols, p.rows, func(i int) int64 { return boolToInt(raw[i]) })
+	p.mask = make([]bool, len(raw))
+	for y := 0; y < p.rows; y++ {
+		for x := 0; x < p.cols; x++ {
+			window := image.Rect(x-1, y-1, x+2, y+2).Intersect(image.Rect(0, 0, p.cols, p.rows))
+			p.mask[y*p.cols+x] = 2*sumRect(rawSum, p.cols, window) > int64(window.Dx()*window.Dy())
+		}
+	}
+
+	p.lumaSum = integrate(p.cols, p.rows, func(i int) int64 { return int64(luma[i]) })
+	p.maskSum = integrate(p.cols, p.rows, func(i int) int64 { return boolToInt(p.mask[i]) })
+	return p
+}
+
+// isSkin classifies a pixel by its chrominance, with the Cb and Cr ranges of Chai and
+// Ngan; very dark pixels carry no reliable color
+func isSkin(y, cb, cr uint8) bool {
+	return y > 40 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
+
+// regions labels 4-connected groups of skin pixels
+func (p *skinPlane) regions() []skinRegion {
+	seen := make([]bool, len(p.mask))
+	var regions []skinRegion
+	var stack []int
+
+	for start := range p.mask {
+		if !p.mask[start] || seen[start] {
+			continue
+		}
+
+		region := skinRegion{minX: p.cols, minY: p.rows, maxX: -1, maxY: -1}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			x, y := i%p.cols, i/p.cols
+			region.minX, region.maxX = min(region.minX, x), max(region.maxX, x)
+			region.minY, region.maxY = min(region.minY, y), max(region.maxY, y)
+			region.pixels++
+
+			for _, n := range [4]int{i - 1, i + 1, i - p.cols, i + p.cols} {
+				if n < 0 || n >= len(p.mask) || seen[n] || !p.mask[n] {
+					continue
+				}
+				// Horizontal neighbors must stay on the same row
+				if (n == i-1 || n == i+1) && n/p.cols != y {
+					continue
+				}
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// skinFraction returns the fraction of skin pixels in box
+func (p *skinPlane) skinFraction(box image.Rectangle) float32 {
+	return float32(sumRect(p.maskSum, p.cols, box)) / float32(box.Dx()*box.Dy())
+}
+
+// faceScore rates how well the brightness inside box matches a frontal face, from 0 to 1.
+// Each feature compares the mean luma of two areas given as fractions of the box.
+func (p *skinPlane) faceScore(box image.Rectangle) float32 {
+	mean := func(x0, y0, x1, y1 float64) float64 {
+		area := image.Rect(
+			box.Min.X+int(x0*float64(box.Dx())), box.Min.Y+int(y0*float64(box.Dy())),
+			box.Min.X+int(math.Ceil(x1*float64(box.Dx()))), box.Min.Y+int(math.Ceil(y1*float64(box.Dy()))),
+		)
+		if area.Empty() {
+			return 0
+		}
+		return float64(sumRect(p.lumaSum, p.cols, area)) / float64(area.Dx()*area.Dy())
+	}
+
+	eyes := (mean(0.15, 0.27, 0.42, 0.43) + mean(0.58, 0.27, 0.85, 0.43)) / 2
+	eyeBand := mean(0.1, 0.27, 0.9, 0.43)
+	bridge := mean(0.42, 0.27, 0.58, 0.43)
+	cheeks := mean(0.1, 0.45, 0.9, 0.62)
+	mouth := mean(0.3, 0.65, 0.7, 0.77)
+	aroundMouth := (mean(0.3, 0.55, 0.7, 0.64) + mean(0.3, 0.78, 0.7, 0.88)) / 2
+
+	// Relative contrasts keep the features independent of exposure
+	features := []float64{
+		contrast(cheeks, eyeBand),
+		contrast(bridge, eyes),
+		contrast(aroundMouth, mouth),
+	}
+
+	var score float64
+	for _, f := range features {
+		score += math.Min(math.Max(f/skinFeatureContrast, 0), 1)
+	}
+	return float32(score / float64(len(features)))
+}
+
+// skinFeatureContrast is the relative contrast at which a Haar-like feature fully matches
+const skinFeatureContrast = 0.12
+
+// contrast returns how much darker dark is than light, relative to light
+func contrast(light, dark float64) float64 {
+	if light <= 0 {
+		return 0
+	}
+	return (light - dark) / light
+}
+
+// integrate builds the integral image of a cols x rows grid, with a zero first row and
+// column so sumRect needs no bounds checks
+func integrate(cols, rows int, value func(i int) int64) []int64 {
+	sum := make([]int64, (cols+1)*(rows+1))
+	for y := 0; y < rows; y++ {
+		var row int64
+		for x := 0; x < cols; x++ {
+			row += value(y*cols + x)
+			sum[(y+1)*(cols+1)+x+1] = sum[y*(cols+1)+x+1] + row
+		}
+	}
+	return sum
+}
+
+// sumRect returns the sum of the values inside r from an integral image
+func sumRect(sum []int64, cols int, r image.Rectangle) int64 {
+	stride := cols + 1
+	return sum[r.Max.Y*stride+r.Max.X] - sum[r.Min.Y*stride+r.Max.X] -
+		sum[r.Max.Y*stride+r.Min.X] + sum[r.Min.Y*stride+r.Min.X]
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"face-recognition-api/internal/models"
+)
+
+// RenderSVGOverlay builds a standalone SVG document describing the detected faces in image
+// coordinates. The document is sized to the source image so it can be layered over the
+// original photo by the client. Each face is a group holding its shape, landmarks and label,
+// with class names and data attributes the client can use to toggle or restyle them.
+func (ip *ImageProcessor) RenderSVGOverlay(ctx context.Context, faces []models.Face, metadata models.ImageMetadata, opts CircleOptions) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		metadata.Width, metadata.Height, metadata.Width, metadata.Height)
+	fmt.Fprintf(&b, `<g class="faces" fill="none" stroke="%s" stroke-opacity="%s" stroke-width="%d">`,
+		svgColor(opts.Color), svgOpacity(opts.Color), opts.LineWidth)
+
+	for i, face := range faces {
+		centerX := float64(face.X) + float64(face.Width)/2
+		centerY := float64(face.Y) + float64(face.Height)/2
+		radius := math.Max(float64(face.Width), float64(face.Height)) / 2
+
+		fmt.Fprintf(&b, `<g class="face" data-index="%d" data-confidence="%.2f">`, i, face.Confidence)
+		fmt.Fprintf(&b, `<circle class="face-shape" cx="%.1f" cy="%.1f" r="%.1f"/>`, centerX, centerY, radius)
+
+		if len(face.Landmarks) > 0 {
+			fmt.Fprintf(&b, `<g class="face-landmarks" fill="%s" fill-opacity="%s" stroke="none">`,
+				svgColor(opts.Color), svgOpacity(opts.Color))
+			pointRadius := math.Max(2, radius/30)
+			for _, landmark := range face.Landmarks {
+				fmt.Fprintf(&b, `<circle class="face-landmark" data-name="%s" cx="%d" cy="%d" r="%.1f"/>`,
+					landmark.Name, landmark.X, landmark.Y, pointRadius)
+			}
+			b.WriteString(`</g>`)
+		}
+
+		// Place the label above the face, or below it when the face touches the top edge
+		fontSize := math.Max(12, radius/4)
+		labelY := centerY - radius - float64(opts.LineWidth) - fontSize/4
+		if labelY < fontSize {
+			labelY = centerY + radius + float64(opts.LineWidth) + fontSize
+		}
+		fmt.Fprintf(&b, `<text class="face-label" x="%.1f" y="%.1f" font-size="%.1f" text-anchor="middle" fill="%s" fill-opacity="%s" stroke="none">Face %d (%.1f)</text>`,
+			centerX, labelY, fontSize, svgColor(opts.Color), svgOpacity(opts.Color), i+1, face.Confidence)
+
+		b.WriteString(`</g>`)
+	}
+
+	b.WriteString(`</g></svg>`)
+
+	ip.logger.WithContext(ctx).WithField("faces_processed", len(faces)).Info("SVG overlay rendered successfully")
+
+	return b.String()
+}
+
+// svgColor formats the opaque part of a color as an SVG color value
+func svgColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// svgOpacity formats the alpha of a color as an SVG opacity value
+func svgOpacity(c color.NRGBA) string {
+	return fmt.Sprintf("%.3g", float64(c.A)/255)
+}
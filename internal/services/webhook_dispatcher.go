@@ -0,0 +1,364 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"face-recognition-api/internal/config"
+	"face-recognition-api/internal/models"
+	"face-recognition-api/internal/requestid"
+)
+
+// Webhook headers sent with every callback
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookEventHeader     = "X-Webhook-Event"
+)
+
+// Webhook dispatcher errors
+var (
+	ErrCallbacksDisabled  = errors.New("callbacks are disabled: no webhook secret configured")
+	ErrInvalidCallbackURL = errors.New("invalid callback URL")
+	ErrWebhookQueueFull   = errors.New("webhook queue is full")
+	ErrDispatcherStopped  = errors.New("webhook dispatcher is stopped")
+)
+
+// webhookDelivery is one signed callback waiting to be sent
+type webhookDelivery struct {
+	url       string
+	event     models.WebhookEvent
+	body      []byte
+	attempts  int
+	requestID string
+}
+
+// deadLetter is the record written for a callback that could not be delivered
+type deadLetter struct {
+	URL       string          `json:"url"`
+	ID        string          `json:"id"`
+	RequestID string          `json:"request_id,omitempty"`
+	Event     string          `json:"event"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	FailedAt  time.Time       `json:"failed_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// WebhookDispatcher delivers signed callbacks with retries on a bounded worker pool
+type WebhookDispatcher struct {
+	client *http.Client
+	config config.WebhookConfig
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	queue   chan *webhookDelivery
+	stopped bool
+
+	// sending counts deliveries being attempted or waiting to retry
+	sending atomic.Int64
+
+	deadLetterMu sync.Mutex
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher instance
+func NewWebhookDispatcher(cfg config.WebhookConfig, logger *logrus.Logger) *WebhookDispatcher {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &WebhookDispatcher{
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Redirects could point a validated public URL at a private address
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: cfg,
+		logger: logger,
+		queue:  make(chan *webhookDelivery, max(cfg.QueueSize, 1)),
+		ctx:    ctx,
+		stop:   stop,
+	}
+}
+
+// Stats reports the number of queued callbacks and of deliveries in progress, including
+// those waiting to retry
+func (d *WebhookDispatcher) Stats() (queued, sending int) {
+	return len(d.queue), int(d.sending.Load())
+}
+
+// Start starts the delivery workers
+func (d *WebhookDispatcher) Start() {
+	workers := max(d.config.Workers, 1)
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+}
+
+// Stop stops accepting callbacks and waits for queued and in-flight deliveries until ctx
+// is done. Deliveries still pending at that point are aborted and dead-lettered, and the
+// returned error reports how many there were.
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		abandoned := int64(len(d.queue)) + d.sending.Load()
+		d.stop()
+		<-done
+		return fmt.Errorf("%d webhook deliveries abandoned: %w", abandoned, ctx.Err())
+	}
+}
+
+// ValidateCallbackURL checks that callbacks are enabled and the URL passes SSRF validation
+func (d *WebhookDispatcher) ValidateCallbackURL(callbackURL string) error {
+	if d.config.Secret == "" {
+		return ErrCallbacksDisabled
+	}
+	if err := ValidateURL(callbackURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallbackURL, err)
+	}
+	return nil
+}
+
+// Deliver queues a signed callback carrying data or apiErr for the given event. The request ID
+// in ctx, if any, is logged with every attempt and forwarded to the receiver.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, callbackURL, event string, data interface{}, apiErr *models.APIError) error {
+	id, err := newWebhookID()
+	if err != nil {
+		return err
+	}
+
+	payload := models.WebhookEvent{
+		ID:        id,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+		Error:     apiErr,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delivery := &webhookDelivery{url: callbackURL, event: payload, body: body, requestID: requestid.FromContext(ctx)}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		d.writeDeadLetter(delivery, ErrDispatcherStopped)
+		return ErrDispatcherStopped
+	}
+
+	select {
+	case d.queue <- delivery:
+		return nil
+	default:
+		d.writeDeadLetter(delivery, ErrWebhookQueueFull)
+		return ErrWebhookQueueFull
+	}
+}
+
+// worker sends queued deliveries until the queue is closed
+func (d *WebhookDispatcher) worker() {
+	defer d.wg.Done()
+
+	for delivery := range d.queue {
+		d.sending.Add(1)
+		d.send(delivery)
+		d.sending.Add(-1)
+	}
+}
+
+// send attempts a delivery with exponential backoff, dead-lettering it when attempts run out
+func (d *WebhookDispatcher) send(delivery *webhookDelivery) {
+	maxAttempts := max(d.config.MaxAttempts, 1)
+	backoff := d.config.InitialBackoff
+
+	var lastErr error
+	for delivery.attempts < maxAttempts {
+		if delivery.attempts > 0 {
+			// Full jitter keeps retries from many deliveries from synchronizing
+			wait := time.Duration(mathrand.Int63n(int64(backoff) + 1))
+			select {
+			case <-time.After(wait):
+			case <-d.ctx.Done():
+				d.writeDeadLetter(delivery, fmt.Errorf("aborted on shutdown: %w", lastErr))
+				return
+			}
+			backoff = min(backoff*2, d.config.MaxBackoff)
+		}
+
+		delivery.attempts++
+		retry, err := d.post(delivery)
+		if err == nil {
+			d.log(delivery).WithFields(logrus.Fields{
+				"webhook_id": delivery.event.ID,
+				"event":      delivery.event.Event,
+				"attempts":   delivery.attempts,
+			}).Info("Webhook delivered")
+			return
+		}
+
+		lastErr = err
+		d.log(delivery).WithError(err).WithFields(logrus.Fields{
+			"webhook_id": delivery.event.ID,
+			"event":      delivery.event.Event,
+			"attempt":    delivery.attempts,
+		}).Warn("Webhook delivery attempt failed")
+
+		if !retry {
+			break
+		}
+	}
+
+	d.writeDeadLetter(delivery, lastErr)
+}
+
+// post sends one signed attempt and reports whether a failure is worth retrying
+func (d *WebhookDispatcher) post(delivery *webhookDelivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Face-Recognition-API/1.0")
+	req.Header.Set(WebhookIDHeader, delivery.event.ID)
+	req.Header.Set(WebhookEventHeader, delivery.event.Event)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(d.config.Secret, timestamp, delivery.body))
+	if delivery.requestID != "" {
+		req.Header.Set(requestid.Header, delivery.requestID)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return true, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	default:
+		return false, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+}
+
+// writeDeadLetter logs an undeliverable callback and appends it to the dead-letter file if configured
+func (d *WebhookDispatcher) writeDeadLetter(delivery *webhookDelivery, cause error) {
+	record := deadLetter{
+		URL:       delivery.url,
+		ID:        delivery.event.ID,
+		RequestID: delivery.requestID,
+		Event:     delivery.event.Event,
+		Attempts:  delivery.attempts,
+		FailedAt:  time.Now().UTC(),
+		Payload:   delivery.body,
+	}
+	if cause != nil {
+		record.LastError = cause.Error()
+	}
+
+	d.log(delivery).WithFields(logrus.Fields{
+		"webhook_id":   record.ID,
+		"event":        record.Event,
+		"callback_url": record.URL,
+		"attempts":     record.Attempts,
+		"last_error":   record.LastError,
+	}).Error("Webhook dead-lettered")
+
+	if d.config.DeadLetterPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		d.log(delivery).WithError(err).Error("Failed to encode dead-letter record")
+		return
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+
+	f, err := os.OpenFile(d.config.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		d.log(delivery).WithError(err).Error("Failed to open dead-letter log")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		d.log(delivery).WithError(err).Error("Failed to write dead-letter log")
+	}
+}
+
+// log returns a log entry tagged with the request ID that triggered the delivery
+func (d *WebhookDispatcher) log(delivery *webhookDelivery) *logrus.Entry {
+	return d.logger.WithContext(requestid.NewContext(context.Background(), delivery.requestID))
+}
+
+// SignWebhook computes the hex HMAC-SHA256 of "timestamp.body" with the shared secret.
+// Receivers should recompute it and reject stale timestamps to prevent replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookID returns a random 128-bit hex delivery identifier
+func newWebhookID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}